@@ -2,8 +2,11 @@ package flatjson
 
 import (
 	"bufio"
+	"bytes"
 	"compress/gzip"
 	"encoding/json"
+	"io"
+	"math"
 	"os"
 	"testing"
 
@@ -147,3 +150,165 @@ func loadObjects(b *testing.B, filename string) [][]byte {
 
 	return objects
 }
+
+func BenchmarkCompact(b *testing.B) {
+	b.Run("movies", func(b *testing.B) { benchmarkCompact(b, "testdata/movies.json.gz") })
+	b.Run("logs", func(b *testing.B) { benchmarkCompact(b, "testdata/logs.json.gz") })
+}
+func benchmarkCompact(b *testing.B, filename string) {
+	lines := loadObjects(b, filename)
+	var buf bytes.Buffer
+	b.ResetTimer()
+	for i, line := range lines {
+		b.SetBytes(int64(len(line)))
+		for b.Loop() {
+			buf.Reset()
+			if err := Compact(&buf, line); err != nil {
+				b.Errorf("line %d: %v", i, err)
+			}
+		}
+	}
+}
+
+func BenchmarkEncodingJSONCompact(b *testing.B) {
+	b.Run("movies", func(b *testing.B) { benchmarkEncodingJSONCompact(b, "testdata/movies.json.gz") })
+	b.Run("logs", func(b *testing.B) { benchmarkEncodingJSONCompact(b, "testdata/logs.json.gz") })
+}
+func benchmarkEncodingJSONCompact(b *testing.B, filename string) {
+	lines := loadObjects(b, filename)
+	var buf bytes.Buffer
+	b.ResetTimer()
+	for i, line := range lines {
+		b.SetBytes(int64(len(line)))
+		for b.Loop() {
+			buf.Reset()
+			if err := json.Compact(&buf, line); err != nil {
+				b.Errorf("line %d: %v", i, err)
+			}
+		}
+	}
+}
+
+func BenchmarkTokenizer(b *testing.B) {
+	b.Run("movies", func(b *testing.B) { benchmarkTokenizer(b, "testdata/movies.json.gz") })
+	b.Run("logs", func(b *testing.B) { benchmarkTokenizer(b, "testdata/logs.json.gz") })
+}
+func benchmarkTokenizer(b *testing.B, filename string) {
+	lines := loadObjects(b, filename)
+	var tkz Tokenizer
+	b.ResetTimer()
+	for i, line := range lines {
+		b.SetBytes(int64(len(line)))
+		for b.Loop() {
+			tkz.Reset(line)
+			for {
+				_, err := tkz.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					b.Errorf("line %d: %v", i, err)
+					break
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkEncodingJSONToken(b *testing.B) {
+	b.Run("movies", func(b *testing.B) { benchmarkEncodingJSONToken(b, "testdata/movies.json.gz") })
+	b.Run("logs", func(b *testing.B) { benchmarkEncodingJSONToken(b, "testdata/logs.json.gz") })
+}
+func benchmarkEncodingJSONToken(b *testing.B, filename string) {
+	lines := loadObjects(b, filename)
+	b.ResetTimer()
+	for i, line := range lines {
+		b.SetBytes(int64(len(line)))
+		for b.Loop() {
+			dec := json.NewDecoder(bytes.NewReader(line))
+			for {
+				_, err := dec.Token()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					b.Errorf("line %d: %v", i, err)
+					break
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkCallbacks(b *testing.B) {
+	b.Run("movies", func(b *testing.B) { benchmarkCallbacks(b, "testdata/movies.json.gz") })
+	b.Run("logs", func(b *testing.B) { benchmarkCallbacks(b, "testdata/logs.json.gz") })
+}
+func benchmarkCallbacks(b *testing.B, filename string) {
+	lines := loadObjects(b, filename)
+	var n int
+	cb := &Callbacks{
+		MaxDepth:  math.MaxInt,
+		OnFloat:   func(Prefixes, Float) { n++ },
+		OnInteger: func(Prefixes, Integer) { n++ },
+		OnString:  func(Prefixes, String) { n++ },
+		OnBoolean: func(Prefixes, Bool) { n++ },
+		OnNull:    func(Prefixes, Null) { n++ },
+	}
+	b.ResetTimer()
+	for i, line := range lines {
+		b.SetBytes(int64(len(line)))
+		for b.Loop() {
+			if _, _, err := ScanObject(line, 0, cb); err != nil {
+				b.Errorf("line %d: %v", i, err)
+			}
+		}
+	}
+}
+
+func BenchmarkVisitor(b *testing.B) {
+	b.Run("movies", func(b *testing.B) { benchmarkVisitor(b, "testdata/movies.json.gz") })
+	b.Run("logs", func(b *testing.B) { benchmarkVisitor(b, "testdata/logs.json.gz") })
+}
+func benchmarkVisitor(b *testing.B, filename string) {
+	lines := loadObjects(b, filename)
+	var n int
+	b.ResetTimer()
+	for i, line := range lines {
+		b.SetBytes(int64(len(line)))
+		for b.Loop() {
+			if _, _, err := ScanObjectWith(line, 0, counter{&n}); err != nil {
+				b.Errorf("line %d: %v", i, err)
+			}
+		}
+	}
+}
+
+func BenchmarkScanner(b *testing.B) {
+	b.Run("movies", func(b *testing.B) { benchmarkScanner(b, "testdata/movies.json.gz") })
+	b.Run("logs", func(b *testing.B) { benchmarkScanner(b, "testdata/logs.json.gz") })
+}
+func benchmarkScanner(b *testing.B, filename string) {
+	lines := loadObjects(b, filename)
+	var s Scanner
+	b.ResetTimer()
+	for i, line := range lines {
+		b.SetBytes(int64(len(line)))
+
+		for b.Loop() {
+			_, found, err := s.ScanObject(line, 0, &Callbacks{
+				OnRaw: func(prefixes Prefixes, name Prefix, value Pos) {
+					if !name.IsArrayIndex() && !name.IsObjectKey() {
+						panic("what")
+					}
+				},
+			})
+			if err != nil {
+				b.Errorf("line %d: %v", i, err)
+			}
+			if !found {
+				b.Errorf("should have found an object")
+			}
+		}
+	}
+}
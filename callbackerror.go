@@ -0,0 +1,70 @@
+package flatjson
+
+import "fmt"
+
+// CallbackError is the error a callback returned, which stopped the
+// scan.
+type CallbackError struct {
+	// Prefixes leading to the value, its own name last.
+	Prefixes Prefixes
+	// Pos of the value.
+	Pos Pos
+	// Err the callback returned.
+	Err error
+}
+
+func (e *CallbackError) Error() string {
+	return fmt.Sprintf("callback failed at offset %d: %v", e.Pos.From, e.Err)
+}
+
+func (e *CallbackError) Unwrap() error { return e.Err }
+
+// failable tells if any callback can fail.
+func (cb *Callbacks) failable() bool {
+	return cb.OnFloatErr != nil || cb.OnIntegerErr != nil || cb.OnStringErr != nil ||
+		cb.OnBooleanErr != nil || cb.OnNullErr != nil || cb.OnValueErr != nil
+}
+
+// reportErr calls the callbacks that can fail on val, and wraps the
+// first error in a *CallbackError.
+func (cb *Callbacks) reportErr(prefixes Prefixes, val Value) error {
+	var err error
+	switch val.Kind {
+	case EntityType_String:
+		if cb.OnStringErr != nil {
+			err = cb.OnStringErr(prefixes, String{Name: val.Name, Value: val.Pos})
+		}
+	case EntityType_Number:
+		if val.num.isInt && cb.OnIntegerErr != nil {
+			err = cb.OnIntegerErr(prefixes, Integer{Name: val.Name, Value: val.num.i64})
+		} else if cb.OnFloatErr != nil {
+			err = cb.OnFloatErr(prefixes, Float{Name: val.Name, Value: val.num.f64})
+		}
+	case EntityType_Boolean_True, EntityType_Boolean_False:
+		if cb.OnBooleanErr != nil {
+			err = cb.OnBooleanErr(prefixes, Bool{Name: val.Name, Value: val.Kind == EntityType_Boolean_True})
+		}
+	case EntityType_Null:
+		if cb.OnNullErr != nil {
+			err = cb.OnNullErr(prefixes, Null{Name: val.Name})
+		}
+	}
+	if err == nil && cb.OnValueErr != nil {
+		err = cb.OnValueErr(prefixes, val)
+	}
+	if err == nil {
+		return nil
+	}
+	path := append(make(Prefixes, 0, len(prefixes)+1), prefixes...)
+	return &CallbackError{Prefixes: append(path, val.Name), Pos: val.Pos, Err: err}
+}
+
+// nestedErr is the error of the scan of a nested object or array,
+// starting at i. Syntax errors are wrapped to tell where the nested
+// value began, others stop the scan as they are.
+func nestedErr(i int, msg string, err error) error {
+	if serr, ok := err.(*SyntaxError); ok {
+		return syntaxErr(i, msg, serr)
+	}
+	return err
+}
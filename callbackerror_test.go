@@ -0,0 +1,123 @@
+package flatjson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var errUnknownStatus = errors.New("unknown status")
+
+func TestCallbackError(t *testing.T) {
+	data := []byte(`{"ok":true,"items":[{"status":"done"},{"status":"lost"},{"status":"late"}]}`)
+	var seen []string
+	_, _, err := ScanObject(data, 0, &Callbacks{
+		MaxDepth: 3,
+		OnStringErr: func(prefixes Prefixes, val String) error {
+			seen = append(seen, val.Value.String(data))
+			if !val.Equal(data, "done") {
+				return errUnknownStatus
+			}
+			return nil
+		},
+	})
+	var cerr *CallbackError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("want a callback error, got %v", err)
+	}
+	if !errors.Is(err, errUnknownStatus) {
+		t.Errorf("want %v, got %v", errUnknownStatus, cerr.Err)
+	}
+	if want, got := "items.1.status", cerr.Prefixes.AsString(data); want != got {
+		t.Errorf("want path %q, got %q", want, got)
+	}
+	if want, got := `"lost"`, cerr.Pos.String(data); want != got {
+		t.Errorf("want value %s, got %s", want, got)
+	}
+	if want, got := `"done" "lost"`, strings.Join(seen, " "); want != got {
+		t.Errorf("want to stop right away, seen %s", got)
+	}
+}
+
+func TestCallbackErrorKinds(t *testing.T) {
+	fail := errors.New("fail")
+	tests := []struct {
+		Name     string
+		Data     string
+		Cb       Callbacks
+		WantPath string
+		WantPos  Pos
+	}{
+		{
+			Name:     "integer",
+			Data:     `{"a":1.5,"b":2}`,
+			Cb:       Callbacks{OnIntegerErr: func(Prefixes, Integer) error { return fail }},
+			WantPath: "b",
+			WantPos:  Pos{13, 14},
+		},
+		{
+			Name:     "float",
+			Data:     `{"a":[true,1.5]}`,
+			Cb:       Callbacks{MaxDepth: 1, OnFloatErr: func(Prefixes, Float) error { return fail }},
+			WantPath: "a.1",
+			WantPos:  Pos{11, 14},
+		},
+		{
+			Name:     "boolean",
+			Data:     `{"a":null,"b":false}`,
+			Cb:       Callbacks{OnBooleanErr: func(Prefixes, Bool) error { return fail }},
+			WantPath: "b",
+			WantPos:  Pos{14, 19},
+		},
+		{
+			Name:     "null",
+			Data:     `[0,null]`,
+			Cb:       Callbacks{OnNullErr: func(Prefixes, Null) error { return fail }},
+			WantPath: "1",
+			WantPos:  Pos{3, 7},
+		},
+		{
+			Name: "object",
+			Data: `{"a":{"b":{}}}`,
+			Cb: Callbacks{MaxDepth: 1, OnValueErr: func(_ Prefixes, v Value) error {
+				if v.Kind == EntityType_Object {
+					return fail
+				}
+				return nil
+			}},
+			WantPath: "a.b",
+			WantPos:  Pos{10, 12},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			data := []byte(tt.Data)
+			var err error
+			if data[0] == '[' {
+				_, _, err = ScanArray(data, 0, &tt.Cb)
+			} else {
+				_, _, err = ScanObject(data, 0, &tt.Cb)
+			}
+			var cerr *CallbackError
+			if !errors.As(err, &cerr) || cerr.Err != fail {
+				t.Fatalf("want a callback error, got %v", err)
+			}
+			if want, got := tt.WantPath, cerr.Prefixes.AsString(data); want != got {
+				t.Errorf("want path %q, got %q", want, got)
+			}
+			if want, got := tt.WantPos, cerr.Pos; want != got {
+				t.Errorf("want pos %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestCallbackErrorDepth(t *testing.T) {
+	data := []byte(`{"a":{"b":"c"}}`)
+	_, _, err := ScanObject(data, 0, &Callbacks{
+		OnStringErr: func(Prefixes, String) error { return errUnknownStatus },
+	})
+	if err != nil {
+		t.Errorf("want no callback deeper than MaxDepth, got %v", err)
+	}
+}
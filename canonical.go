@@ -0,0 +1,182 @@
+package flatjson
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	// ErrDuplicateKey is returned when an object has the same key more
+	// than once where that's not allowed.
+	ErrDuplicateKey = errors.New("duplicate key")
+	// ErrInvalidUTF8 is returned when a string isn't valid UTF-8 where
+	// that's not allowed.
+	ErrInvalidUTF8 = errors.New("invalid UTF-8")
+)
+
+// Canonicalize re-emits a document in the JSON Canonicalization Scheme
+// of RFC 8785: without insignificant whitespace, with the members of
+// objects sorted by the UTF-16 code units of their keys, with strings
+// escaped as little as possible and with numbers serialized like
+// ECMAScript does.
+//
+// The document must be I-JSON (RFC 7493): no duplicate keys, only valid
+// UTF-8 with no escaped lone surrogates, and numbers that fit in a
+// float64.
+func Canonicalize(data []byte) ([]byte, error) {
+	var doc Document
+	if err := doc.Reset(data); err != nil {
+		return nil, err
+	}
+	return appendCanonical(make([]byte, 0, len(data)), doc.Root())
+}
+
+type canonicalMember struct {
+	key   string
+	value Node
+}
+
+func appendCanonical(dst []byte, n Node) ([]byte, error) {
+	var err error
+	switch n.Type() {
+	case EntityType_Object:
+		members := make([]canonicalMember, 0, n.Len())
+		n.Iterate(func(name Prefix, child Node) bool {
+			var key []byte
+			if key, err = unquoteValidUTF8(name.Bytes(n.doc.data)); err != nil {
+				return false
+			}
+			members = append(members, canonicalMember{key: string(key), value: child})
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		slices.SortFunc(members, func(a, b canonicalMember) int {
+			return compareUTF16(a.key, b.key)
+		})
+		dst = append(dst, '{')
+		for k, m := range members {
+			if k > 0 {
+				if members[k-1].key == m.key {
+					return nil, ErrDuplicateKey
+				}
+				dst = append(dst, ',')
+			}
+			if dst, err = appendCanonicalString(dst, []byte(m.key)); err != nil {
+				return nil, err
+			}
+			dst = append(dst, ':')
+			if dst, err = appendCanonical(dst, m.value); err != nil {
+				return nil, err
+			}
+		}
+		return append(dst, '}'), nil
+
+	case EntityType_Array:
+		dst = append(dst, '[')
+		k := 0
+		n.Iterate(func(_ Prefix, child Node) bool {
+			if k > 0 {
+				dst = append(dst, ',')
+			}
+			k++
+			dst, err = appendCanonical(dst, child)
+			return err == nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return append(dst, ']'), nil
+
+	case EntityType_String:
+		s, err := unquoteValidUTF8(n.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		return appendCanonicalString(dst, s)
+
+	case EntityType_Number:
+		f, err := strconv.ParseFloat(unsafeBytesToString(n.Bytes()), 64)
+		if err != nil {
+			return nil, err
+		}
+		return appendECMAScriptNumber(dst, f), nil
+	}
+	// literals have a single form
+	return append(dst, n.Bytes()...), nil
+}
+
+// unquoteValidUTF8 unquotes a key or string of I-JSON, where neither
+// invalid UTF-8 nor lone surrogates can be.
+func unquoteValidUTF8(raw []byte) ([]byte, error) {
+	if !utf8.Valid(raw) {
+		return nil, ErrInvalidUTF8
+	}
+	return Unquoter{LoneSurrogates: SurrogateError, InvalidUTF8: UTF8Error}.Unquote(raw)
+}
+
+func appendCanonicalString(dst, s []byte) ([]byte, error) {
+	if !utf8.Valid(s) {
+		return nil, ErrInvalidUTF8
+	}
+	return appendQuote(dst, unsafeBytesToString(s)), nil
+}
+
+// appendECMAScriptNumber appends f like ECMAScript's Number.toString.
+func appendECMAScriptNumber(dst []byte, f float64) []byte {
+	if f == 0 {
+		// also for -0
+		return append(dst, '0')
+	}
+	abs := math.Abs(f)
+	if abs >= 1e-6 && abs < 1e21 {
+		return strconv.AppendFloat(dst, f, 'f', -1, 64)
+	}
+	start := len(dst)
+	dst = strconv.AppendFloat(dst, f, 'e', -1, 64)
+	// ECMAScript doesn't pad the exponent to 2 digits
+	for i := len(dst) - 1; i > start; i-- {
+		if dst[i] == 'e' {
+			if len(dst)-i == 4 && dst[i+2] == '0' {
+				dst = append(dst[:i+2], dst[i+3])
+			}
+			break
+		}
+	}
+	return dst
+}
+
+// compareUTF16 orders strings by their UTF-16 code units.
+func compareUTF16(a, b string) int {
+	var ua, ub [2]rune
+	for a != "" && b != "" {
+		ra, na := utf8.DecodeRuneInString(a)
+		rb, nb := utf8.DecodeRuneInString(b)
+		a, b = a[na:], b[nb:]
+		if ra == rb {
+			continue
+		}
+		ca, cb := utf16Units(ra, &ua), utf16Units(rb, &ub)
+		for i := 0; i < len(ca) && i < len(cb); i++ {
+			if ca[i] != cb[i] {
+				return int(ca[i]) - int(cb[i])
+			}
+		}
+		return len(ca) - len(cb)
+	}
+	return len(a) - len(b)
+}
+
+func utf16Units(r rune, buf *[2]rune) []rune {
+	if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+		buf[0], buf[1] = r1, r2
+		return buf[:2]
+	}
+	buf[0] = r
+	return buf[:1]
+}
@@ -0,0 +1,123 @@
+package flatjson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		Name string
+		Data string
+		Want string
+	}{
+		{
+			// RFC 8785, section 3.2.2
+			Name: "rfc example",
+			Data: `{
+  "numbers": [333333333.33333329, 1E30, 4.50,
+              2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`,
+			Want: `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+		},
+		{
+			// RFC 8785, section 3.2.3
+			Name: "sorting by utf-16",
+			Data: `{
+  "€": "Euro Sign",
+  "\r": "Carriage Return",
+  "דּ": "Hebrew Letter Dalet With Dagesh",
+  "1": "One",
+  "😀": "Emoji: Grinning Face",
+  "\u0080": "Control",
+  "ö": "Latin Small Letter O With Diaeresis"
+}`,
+			Want: `{"\r":"Carriage Return","1":"One","` + "\u0080" + `":"Control","ö":"Latin Small Letter O With Diaeresis","€":"Euro Sign","😀":"Emoji: Grinning Face","` + "\ufb33" + `":"Hebrew Letter Dalet With Dagesh"}`,
+		},
+		{
+			// RFC 8785, appendix B
+			Name: "smallest number",
+			Data: `[5e-324, -5e-324, 0e-400]`,
+			Want: `[5e-324,-5e-324,0]`,
+		},
+		{
+			Name: "nested",
+			Data: ` { "b" : [ {"d":1, "c":2} ], "a" : {} } `,
+			Want: `{"a":{},"b":[{"c":2,"d":1}]}`,
+		},
+		{
+			Name: "scalar",
+			Data: ` "A" `,
+			Want: `"A"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			got, err := Canonicalize([]byte(tt.Data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.Want {
+				t.Errorf("want %s", tt.Want)
+				t.Errorf(" got %s", got)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeErrors(t *testing.T) {
+	tests := []struct {
+		Data    string
+		WantErr error
+	}{
+		{Data: `{"a":1,"b":2,"a":3}`, WantErr: ErrDuplicateKey},
+		{Data: `{"a":"A","a":2}`, WantErr: ErrDuplicateKey},
+		{Data: "[\"\xff\"]", WantErr: ErrInvalidUTF8},
+		{Data: `["\ud800"]`},
+		{Data: `{"\udc00":1}`},
+		{Data: `[1e400]`},
+		{Data: `{"a":}`},
+	}
+	for _, tt := range tests {
+		_, err := Canonicalize([]byte(tt.Data))
+		if err == nil {
+			t.Errorf("%s: want an error", tt.Data)
+		} else if tt.WantErr != nil && !errors.Is(err, tt.WantErr) {
+			t.Errorf("%s: want error %v, got %v", tt.Data, tt.WantErr, err)
+		}
+	}
+}
+
+func TestAppendECMAScriptNumber(t *testing.T) {
+	tests := []struct {
+		In   float64
+		Want string
+	}{
+		{In: 0, Want: "0"},
+		{In: negativeZero(), Want: "0"},
+		{In: 1, Want: "1"},
+		{In: -1.5, Want: "-1.5"},
+		{In: 1e21, Want: "1e+21"},
+		{In: 1e20, Want: "100000000000000000000"},
+		{In: 123456789012345680000, Want: "123456789012345680000"},
+		{In: 1e-6, Want: "0.000001"},
+		{In: 1e-7, Want: "1e-7"},
+		{In: 123e-20, Want: "1.23e-18"},
+		{In: 1.7976931348623157e308, Want: "1.7976931348623157e+308"},
+		{In: 5e-324, Want: "5e-324"},
+		{In: 9007199254740993, Want: "9007199254740992"},
+		{In: 0.30000000000000004, Want: "0.30000000000000004"},
+	}
+	for _, tt := range tests {
+		if got := string(appendECMAScriptNumber(nil, tt.In)); got != tt.Want {
+			t.Errorf("%v: want %s, got %s", tt.In, tt.Want, got)
+		}
+	}
+}
+
+func negativeZero() float64 {
+	zero := 0.0
+	return -zero
+}
@@ -0,0 +1,155 @@
+// Command flatjson works with JSON documents using the flatjson package.
+//
+// Usage:
+//
+//	flatjson diff [flags] a.json b.json
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aybabtme/flatjson"
+)
+
+func main() {
+	code, err := run(os.Args[1:], os.Stdout, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "flatjson:", err)
+	}
+	os.Exit(code)
+}
+
+const usage = `usage: flatjson <command> [flags] [args]
+
+commands:
+  diff    compare two JSON documents
+`
+
+func run(args []string, stdout, stderr io.Writer) (int, error) {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2, nil
+	}
+	switch args[0] {
+	case "diff":
+		return runDiff(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0, nil
+	}
+	fmt.Fprint(stderr, usage)
+	return 2, fmt.Errorf("unknown command %q", args[0])
+}
+
+// keyFlags collects `path=field` pairs.
+type keyFlags map[string]string
+
+func (k keyFlags) String() string { return "" }
+
+func (k keyFlags) Set(v string) error {
+	path, field, ok := strings.Cut(v, "=")
+	if !ok {
+		return errors.New("want path=field")
+	}
+	k[path] = field
+	return nil
+}
+
+func runDiff(args []string, stdout, stderr io.Writer) (int, error) {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		color     = fs.String("color", "auto", "colorize the output: auto, always or never")
+		unordered = fs.Bool("unordered", false, "compare arrays as sets")
+		keys      = keyFlags{}
+	)
+	fs.Var(keys, "key", "compare the arrays at `path=field` as sets of objects identified by field (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: flatjson diff [flags] a.json b.json")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2, nil
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2, nil
+	}
+	useColor, err := wantColor(*color, stdout)
+	if err != nil {
+		return 2, err
+	}
+	a, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return 2, err
+	}
+	b, err := os.ReadFile(fs.Arg(1))
+	if err != nil {
+		return 2, err
+	}
+	changes, err := flatjson.DiffWith(a, b, &flatjson.DiffOptions{
+		UnorderedArrays: *unordered,
+		ArrayKeys:       keys,
+	})
+	if err != nil {
+		return 2, err
+	}
+	printChanges(stdout, a, b, changes, useColor)
+	if len(changes) > 0 {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+)
+
+func printChanges(w io.Writer, a, b []byte, changes []flatjson.Change, useColor bool) {
+	paint := func(color, s string) string {
+		if !useColor {
+			return s
+		}
+		return color + s + colorReset
+	}
+	for _, c := range changes {
+		path := strings.Join(c.Path, ".")
+		if path == "" {
+			path = "."
+		}
+		switch c.Kind {
+		case flatjson.ChangeAdded:
+			fmt.Fprintln(w, paint(colorGreen, "+ "+path+": "+c.New.String(b)))
+		case flatjson.ChangeRemoved:
+			fmt.Fprintln(w, paint(colorRed, "- "+path+": "+c.Old.String(a)))
+		default:
+			fmt.Fprintln(w, paint(colorYellow, "~ "+path+": ")+
+				paint(colorRed, c.Old.String(a))+" -> "+paint(colorGreen, c.New.String(b)))
+		}
+	}
+}
+
+func wantColor(mode string, w io.Writer) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		f, ok := w.(*os.File)
+		if !ok || os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		fi, err := f.Stat()
+		return err == nil && fi.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, fmt.Errorf("invalid -color %q, want auto, always or never", mode)
+}
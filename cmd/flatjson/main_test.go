@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.json")
+	b := filepath.Join(dir, "b.json")
+	if err := os.WriteFile(a, []byte(`{"a":1,"b":[1,2],"c":"x"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte(`{"a":2,"b":[2,1],"d":true}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name     string
+		Args     []string
+		WantCode int
+		Want     string
+	}{
+		{
+			Name:     "plain",
+			Args:     []string{"diff", "-color=never", a, b},
+			WantCode: 1,
+			Want: "~ a: 1 -> 2\n" +
+				"~ b.0: 1 -> 2\n" +
+				"~ b.1: 2 -> 1\n" +
+				"- c: \"x\"\n" +
+				"+ d: true\n",
+		},
+		{
+			Name:     "unordered",
+			Args:     []string{"diff", "-color=never", "-unordered", a, b},
+			WantCode: 1,
+			Want: "~ a: 1 -> 2\n" +
+				"- c: \"x\"\n" +
+				"+ d: true\n",
+		},
+		{
+			Name:     "colored",
+			Args:     []string{"diff", "-color=always", "-unordered", a, b},
+			WantCode: 1,
+			Want: "\x1b[33m~ a: \x1b[0m\x1b[31m1\x1b[0m -> \x1b[32m2\x1b[0m\n" +
+				"\x1b[31m- c: \"x\"\x1b[0m\n" +
+				"\x1b[32m+ d: true\x1b[0m\n",
+		},
+		{
+			Name:     "no differences",
+			Args:     []string{"diff", a, a},
+			WantCode: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code, err := run(tt.Args, &stdout, &stderr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if code != tt.WantCode {
+				t.Errorf("want exit code %d, got %d (%s)", tt.WantCode, code, stderr.String())
+			}
+			if got := stdout.String(); got != tt.Want {
+				t.Errorf("want output %q", tt.Want)
+				t.Errorf(" got output %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package flatjson
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// contextCheckInterval is how many values a scan goes through between
+// checks of its context.
+const contextCheckInterval = 256
+
+// errCodeDone marks the SyntaxError of a scan stopped by its context,
+// before it's turned into a ContextError, or by an iterator.
+const errCodeDone ErrorCode = 255
+
+const (
+	scanDone    = "context done"
+	scanStopped = "scan stopped"
+)
+
+// ContextError is the error of a scan stopped because its context is
+// done.
+type ContextError struct {
+	// Offset the scan reached.
+	Offset int64
+	// Err is the error of the context.
+	Err error
+}
+
+func (e *ContextError) Error() string {
+	return fmt.Sprintf("stopped at offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *ContextError) Unwrap() error { return e.Err }
+
+// checkDone stops the scan if its context is done, every so often.
+func (st *scanState) checkDone(i int) *SyntaxError {
+	if st.done == nil {
+		return nil
+	}
+	if st.untilCheck--; st.untilCheck > 0 {
+		return nil
+	}
+	st.untilCheck = contextCheckInterval
+	select {
+	case <-st.done:
+		return limitErr(i, errCodeDone, scanDone)
+	default:
+		return nil
+	}
+}
+
+// contextErr turns the error of a scan stopped by ctx into a
+// ContextError.
+func contextErr(ctx context.Context, err error) error {
+	var serr *SyntaxError
+	if !errors.As(err, &serr) || serr.Code != errCodeDone {
+		return err
+	}
+	for serr.SubErr != nil {
+		serr = serr.SubErr
+	}
+	return &ContextError{Offset: int64(serr.Offset), Err: ctx.Err()}
+}
+
+// ScanObjectContext is ScanObject, stopped with a *ContextError when ctx
+// is done. The context is checked every few hundred values, and not
+// within objects and arrays deeper than MaxDepth, which are skipped.
+func ScanObjectContext(ctx context.Context, data []byte, from int, cb *Callbacks) (pos Pos, found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return Pos{From: -1, To: -1}, false, &ContextError{Offset: int64(from), Err: err}
+	}
+	var st scanState
+	pos, found, err = scanObject(data, from, nil, cb, st.init(cb, from, ctx.Done()))
+	return pos, found, contextErr(ctx, err)
+}
+
+// ScanArrayContext is ScanArray, stopped with a *ContextError when ctx
+// is done, like ScanObjectContext.
+func ScanArrayContext(ctx context.Context, data []byte, from int, cb *Callbacks) (pos Pos, found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return Pos{From: -1, To: -1}, false, &ContextError{Offset: int64(from), Err: err}
+	}
+	var st scanState
+	pos, found, err = scanArray(data, from, nil, cb, st.init(cb, from, ctx.Done()))
+	return pos, found, contextErr(ctx, err)
+}
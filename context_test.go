@@ -0,0 +1,128 @@
+package flatjson
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestScanObjectContext(t *testing.T) {
+	data := []byte(`{"list":[` + strings.Repeat("1,", 10000) + `1]}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var seen int
+	_, _, err := ScanObjectContext(ctx, data, 0, &Callbacks{
+		MaxDepth: 2,
+		OnInteger: func(Prefixes, Integer) {
+			if seen++; seen == 1000 {
+				cancel()
+			}
+		},
+	})
+	var cerr *ContextError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("want a context error, got %v", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v, got %v", context.Canceled, cerr.Err)
+	}
+	if seen < 1000 || seen > 1000+contextCheckInterval {
+		t.Errorf("want to stop soon after being canceled, got %d values", seen)
+	}
+	if cerr.Offset <= 2000 || cerr.Offset > int64(2*(1000+contextCheckInterval)+9) {
+		t.Errorf("want to stop soon after being canceled, got offset %d", cerr.Offset)
+	}
+
+	_, _, err = ScanObjectContext(ctx, data, 0, &Callbacks{})
+	if !errors.As(err, &cerr) || cerr.Offset != 0 {
+		t.Errorf("want a context error at 0, got %v", err)
+	}
+
+	seen = 0
+	_, _, err = ScanObjectContext(context.Background(), data, 0, &Callbacks{
+		MaxDepth:  2,
+		OnInteger: func(Prefixes, Integer) { seen++ },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen != 10001 {
+		t.Errorf("want 10001 values, got %d", seen)
+	}
+}
+
+func TestScanArrayContext(t *testing.T) {
+	data := []byte(`[` + strings.Repeat(`{"a":1},`, 10000) + `{}]`)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var seen int
+	_, _, err := ScanArrayContext(ctx, data, 0, &Callbacks{
+		MaxDepth: 1,
+		OnInteger: func(Prefixes, Integer) {
+			if seen++; seen == 10 {
+				cancel()
+			}
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("want %v, got %v", context.Canceled, err)
+	}
+	var serr *SyntaxError
+	if errors.As(err, &serr) {
+		t.Errorf("want the syntax error to be replaced, got %v", serr)
+	}
+}
+
+// cancelingReader cancels its context once it's read past n bytes.
+type cancelingReader struct {
+	r      io.Reader
+	n      int
+	cancel context.CancelFunc
+}
+
+func (r *cancelingReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		r.cancel()
+	}
+	if len(p) > 16 {
+		p = p[:16]
+	}
+	n, err := r.r.Read(p)
+	r.n -= n
+	return n, err
+}
+
+func TestReformatContext(t *testing.T) {
+	in := strings.Repeat(`{"a": 1}`+"\n", 1000)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var out bytes.Buffer
+	err := ReformatContext(ctx, &out, &cancelingReader{r: strings.NewReader(in), n: 100, cancel: cancel}, nil)
+	var cerr *ContextError
+	if !errors.As(err, &cerr) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("want a context error, got %v", err)
+	}
+	if out.Len() == 0 || out.Len() >= len(in) {
+		t.Errorf("want to stop after a few documents, wrote %d bytes", out.Len())
+	}
+	if cerr.Offset < 100 || cerr.Offset > 200 {
+		t.Errorf("want to stop after a few documents, stopped at %d", cerr.Offset)
+	}
+}
+
+func TestRedactorStreamContext(t *testing.T) {
+	r, err := NewRedactor([]RedactRule{{Path: "a", Action: RedactRemove}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = r.StreamContext(ctx, io.Discard, strings.NewReader(`{"a":1}`))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v, got %v", context.Canceled, err)
+	}
+}
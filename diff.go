@@ -0,0 +1,301 @@
+package flatjson
+
+import "strconv"
+
+// ChangeKind is the kind of a Change between two documents.
+type ChangeKind uint8
+
+const (
+	ChangeAdded ChangeKind = iota + 1
+	ChangeRemoved
+	ChangeChanged
+	ChangeTypeChanged
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeChanged:
+		return "changed"
+	case ChangeTypeChanged:
+		return "type-changed"
+	}
+	return "ChangeKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Change is a difference between two documents.
+type Change struct {
+	// Path of the value that changed. Array indices are those of the
+	// first document for removed values, and those of the second
+	// document otherwise.
+	Path []string
+	Kind ChangeKind
+	// Old value in the first document, unless it was added.
+	Old Pos
+	// New value in the second document, unless it was removed.
+	New Pos
+}
+
+// DiffOptions tune how documents are compared.
+type DiffOptions struct {
+	// UnorderedArrays compares arrays as sets: elements are matched
+	// with an equal element anywhere in the other array.
+	UnorderedArrays bool
+	// ArrayKeys compares the arrays at some paths as sets of objects
+	// identified by one of their fields. They map a dotted path pattern,
+	// where `*` matches any key or index, to the name of that field.
+	ArrayKeys map[string]string
+}
+
+// Diff compares two documents and lists the values that differ between
+// them. Members of objects are compared regardless of their order, and
+// arrays are compared element by element.
+func Diff(a, b []byte) ([]Change, error) {
+	return DiffWith(a, b, nil)
+}
+
+// DiffWith compares two documents like Diff does, according to opts.
+func DiffWith(a, b []byte, opts *DiffOptions) ([]Change, error) {
+	aPos, err := locate(a, nil)
+	if err != nil {
+		return nil, err
+	}
+	bPos, err := locate(b, nil)
+	if err != nil {
+		return nil, err
+	}
+	d := differ{a: a, b: b}
+	if opts != nil {
+		d.unordered = opts.UnorderedArrays
+		for pattern, key := range opts.ArrayKeys {
+			d.arrayKeys = append(d.arrayKeys, arrayKey{pattern: SplitPath(pattern), key: key})
+		}
+	}
+	err = d.diff(nil, aPos, bPos)
+	return d.changes, err
+}
+
+type arrayKey struct {
+	pattern []string
+	key     string
+}
+
+type differ struct {
+	a, b      []byte
+	unordered bool
+	arrayKeys []arrayKey
+
+	changes []Change
+}
+
+func (d *differ) add(path []string, kind ChangeKind, old, new Pos) {
+	d.changes = append(d.changes, Change{
+		Path: append([]string(nil), path...),
+		Kind: kind,
+		Old:  old,
+		New:  new,
+	})
+}
+
+func (d *differ) diff(path []string, aPos, bPos Pos) error {
+	at, bt := diffType(d.a, aPos), diffType(d.b, bPos)
+	if at != bt {
+		d.add(path, ChangeTypeChanged, aPos, bPos)
+		return nil
+	}
+	switch at {
+	case EntityType_Object:
+		return d.diffObjects(path, aPos, bPos)
+	case EntityType_Array:
+		return d.diffArrays(path, aPos, bPos)
+	}
+	eq, err := equalValues(d.a, aPos, d.b, bPos)
+	if err == nil && !eq {
+		d.add(path, ChangeChanged, aPos, bPos)
+	}
+	return err
+}
+
+// diffType is the type of a value, where both booleans are the same
+// type.
+func diffType(data []byte, pos Pos) EntityType {
+	et := GuessNextEntityType(data, pos.From)
+	if et == EntityType_Boolean_False {
+		return EntityType_Boolean_True
+	}
+	return et
+}
+
+func (d *differ) diffObjects(path []string, aPos, bPos Pos) error {
+	aMembers, err := objectMembers(d.a, aPos.From)
+	if err != nil {
+		return err
+	}
+	bMembers, err := objectMembers(d.b, bPos.From)
+	if err != nil {
+		return err
+	}
+	// walk the members in the order they appear, for stable results
+	var innerErr error
+	_, err = scanMembers(d.a, aPos.From, func(m member) bool {
+		var key []byte
+		if key, innerErr = Unquote(m.name.Bytes(d.a)); innerErr != nil {
+			return false
+		}
+		if aMembers[string(key)] != m.value {
+			return true // overridden by a duplicate key
+		}
+		sub := append(path, string(key))
+		if bValue, ok := bMembers[string(key)]; ok {
+			innerErr = d.diff(sub, m.value, bValue)
+		} else {
+			d.add(sub, ChangeRemoved, m.value, Pos{})
+		}
+		return innerErr == nil
+	})
+	if err != nil || innerErr != nil {
+		return firstErr(innerErr, err)
+	}
+	_, err = scanMembers(d.b, bPos.From, func(m member) bool {
+		var key []byte
+		if key, innerErr = Unquote(m.name.Bytes(d.b)); innerErr != nil {
+			return false
+		}
+		if _, ok := aMembers[string(key)]; !ok && bMembers[string(key)] == m.value {
+			d.add(append(path, string(key)), ChangeAdded, Pos{}, m.value)
+		}
+		return true
+	})
+	return firstErr(innerErr, err)
+}
+
+func (d *differ) diffArrays(path []string, aPos, bPos Pos) error {
+	aElems, err := arrayElements(d.a, aPos.From)
+	if err != nil {
+		return err
+	}
+	bElems, err := arrayElements(d.b, bPos.From)
+	if err != nil {
+		return err
+	}
+	for _, ak := range d.arrayKeys {
+		if matchPath(ak.pattern, path) {
+			return d.diffKeyedArrays(path, ak.key, aElems, bElems)
+		}
+	}
+	if d.unordered {
+		return d.diffUnorderedArrays(path, aElems, bElems)
+	}
+	for k := 0; k < len(aElems) || k < len(bElems); k++ {
+		sub := append(path, strconv.Itoa(k))
+		switch {
+		case k >= len(bElems):
+			d.add(sub, ChangeRemoved, aElems[k], Pos{})
+		case k >= len(aElems):
+			d.add(sub, ChangeAdded, Pos{}, bElems[k])
+		default:
+			if err := d.diff(sub, aElems[k], bElems[k]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *differ) diffUnorderedArrays(path []string, aElems, bElems []Pos) error {
+	matched := make([]bool, len(aElems))
+	var added []int
+	for j, bElem := range bElems {
+		found := false
+		for k, aElem := range aElems {
+			if matched[k] {
+				continue
+			}
+			eq, err := equalValues(d.a, aElem, d.b, bElem)
+			if err != nil {
+				return err
+			}
+			if eq {
+				matched[k], found = true, true
+				break
+			}
+		}
+		if !found {
+			added = append(added, j)
+		}
+	}
+	for k, aElem := range aElems {
+		if !matched[k] {
+			d.add(append(path, strconv.Itoa(k)), ChangeRemoved, aElem, Pos{})
+		}
+	}
+	for _, j := range added {
+		d.add(append(path, strconv.Itoa(j)), ChangeAdded, Pos{}, bElems[j])
+	}
+	return nil
+}
+
+func (d *differ) diffKeyedArrays(path []string, key string, aElems, bElems []Pos) error {
+	bKeys := make([]Pos, len(bElems))
+	bHasKey := make([]bool, len(bElems))
+	for j, bElem := range bElems {
+		bKeys[j], bHasKey[j] = elementKey(d.b, bElem, key)
+	}
+	matched := make([]bool, len(bElems))
+	for k, aElem := range aElems {
+		aKey, aHasKey := elementKey(d.a, aElem, key)
+		match := -1
+		for j := range bElems {
+			if matched[j] || !aHasKey || !bHasKey[j] {
+				continue
+			}
+			eq, err := equalValues(d.a, aKey, d.b, bKeys[j])
+			if err != nil {
+				return err
+			}
+			if eq {
+				match = j
+				break
+			}
+		}
+		if match < 0 {
+			d.add(append(path, strconv.Itoa(k)), ChangeRemoved, aElem, Pos{})
+			continue
+		}
+		matched[match] = true
+		if err := d.diff(append(path, strconv.Itoa(match)), aElem, bElems[match]); err != nil {
+			return err
+		}
+	}
+	for j, bElem := range bElems {
+		if !matched[j] {
+			d.add(append(path, strconv.Itoa(j)), ChangeAdded, Pos{}, bElem)
+		}
+	}
+	return nil
+}
+
+// elementKey is the position of the value of the field named key of the
+// object at pos, if there's such a field.
+func elementKey(data []byte, pos Pos, key string) (Pos, bool) {
+	if data[pos.From] != '{' {
+		return Pos{}, false
+	}
+	m, found, err := findMember(data, pos.From, key)
+	if err != nil || !found {
+		return Pos{}, false
+	}
+	return m.value, true
+}
+
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package flatjson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		Name string
+		A, B string
+		Opts *DiffOptions
+
+		Want []string
+	}{
+		{
+			Name: "same documents",
+			A:    `{"a":1,"b":[true,null,{"c":"d"}]}`,
+			B:    `{ "b": [true, null, {"c": "d"}], "a": 1.0 }`,
+		},
+		{
+			Name: "scalar changes",
+			A:    `{"a":1,"b":"x","c":true,"d":null}`,
+			B:    `{"a":2,"b":"y","c":false,"d":null}`,
+			Want: []string{
+				`changed a: 1 -> 2`,
+				`changed b: "x" -> "y"`,
+				`changed c: true -> false`,
+			},
+		},
+		{
+			Name: "added and removed keys",
+			A:    `{"a":1,"b":{"c":2,"d":3}}`,
+			B:    `{"b":{"d":3,"e":4},"f":5}`,
+			Want: []string{
+				`removed a: 1 -> `,
+				`removed b.c: 2 -> `,
+				`added b.e:  -> 4`,
+				`added f:  -> 5`,
+			},
+		},
+		{
+			Name: "type changes",
+			A:    `{"a":1,"b":{},"c":"1"}`,
+			B:    `{"a":"1","b":[],"c":"1"}`,
+			Want: []string{
+				`type-changed a: 1 -> "1"`,
+				`type-changed b: {} -> []`,
+			},
+		},
+		{
+			Name: "ordered arrays",
+			A:    `{"a":[1,2,3]}`,
+			B:    `{"a":[1,3]}`,
+			Want: []string{
+				`changed a.1: 2 -> 3`,
+				`removed a.2: 3 -> `,
+			},
+		},
+		{
+			Name: "unordered arrays",
+			A:    `{"a":[1,2,3,3]}`,
+			B:    `{"a":[3,1,4,3]}`,
+			Opts: &DiffOptions{UnorderedArrays: true},
+			Want: []string{
+				`removed a.1: 2 -> `,
+				`added a.2:  -> 4`,
+			},
+		},
+		{
+			Name: "arrays keyed by a field",
+			A:    `{"users":[{"id":1,"name":"a"},{"id":2,"name":"b"},{"id":3}]}`,
+			B:    `{"users":[{"id":2,"name":"c"},{"id":1,"name":"a"},{"id":4}]}`,
+			Opts: &DiffOptions{ArrayKeys: map[string]string{"users": "id"}},
+			Want: []string{
+				`changed users.0.name: "b" -> "c"`,
+				`removed users.2: {"id":3} -> `,
+				`added users.2:  -> {"id":4}`,
+			},
+		},
+		{
+			Name: "arrays keyed by an escaped field",
+			A:    `{"users":[{"id":"a","n":1},{"id":1}]}`,
+			B:    `{"users":[{"id":1.0},{"id":"\u0061","n":2}]}`,
+			Opts: &DiffOptions{ArrayKeys: map[string]string{"users": "id"}},
+			Want: []string{
+				`changed users.1.n: 1 -> 2`,
+			},
+		},
+		{
+			Name: "nested arrays keyed by a field",
+			A:    `[{"items":[{"sku":"x","n":1},{"sku":"y","n":1}]}]`,
+			B:    `[{"items":[{"sku":"y","n":1},{"sku":"x","n":2}]}]`,
+			Opts: &DiffOptions{ArrayKeys: map[string]string{"*.items": "sku"}},
+			Want: []string{
+				`changed 0.items.1.n: 1 -> 2`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			a, b := []byte(tt.A), []byte(tt.B)
+			changes, err := DiffWith(a, b, tt.Opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range changes {
+				got = append(got, c.Kind.String()+" "+strings.Join(c.Path, ".")+": "+
+					c.Old.String(a)+" -> "+c.New.String(b))
+			}
+			if !reflect.DeepEqual(tt.Want, got) {
+				t.Errorf("want %q", tt.Want)
+				t.Errorf(" got %q", got)
+			}
+		})
+	}
+}
+
+func TestDiffTrailingData(t *testing.T) {
+	if _, err := Diff([]byte(`{"a":1} junk`), []byte(`{"a":1}`)); err == nil {
+		t.Errorf("want an error")
+	}
+	if _, err := Diff([]byte(`{"a":1}`), []byte("{\"a\":1}\n")); err != nil {
+		t.Errorf("want no error, got %v", err)
+	}
+}
@@ -0,0 +1,363 @@
+package flatjson
+
+// Document is a parsed JSON document, laid out as a flat tape of
+// values in the order they appear. It doesn't copy any of the data it
+// was parsed from, it only remembers where each value can be found in
+// it, so the data must not be modified while the document is in use.
+//
+// A Document can be reused with Reset; once its tape has grown to the
+// size of the documents it parses, reparsing allocates nothing.
+type Document struct {
+	// UTF8 is what to do with keys and strings that aren't valid UTF-8.
+	// With UTF8Error, Reset fails at the first invalid byte. Str decodes
+	// strings with it.
+	UTF8 UTF8Policy
+
+	data []byte
+	tape []tapeEntry
+
+	// scratch space for Iterate
+	stack    []int
+	prefixes []Prefix
+}
+
+type tapeEntry struct {
+	typ  EntityType
+	name Prefix
+	pos  Pos
+	// children is the number of direct children of a container
+	children int
+	// end is the tape index one past the last descendant of the value
+	end int
+}
+
+const (
+	expectCommaOrClosingBracket       = "expecting a comma or the end of the object"
+	expectCommaOrClosingSquareBracket = "expecting a comma or the end of the array"
+	unexpectedDataAfterValue          = "unexpected data after the end of the document"
+)
+
+// Parse a JSON document. The root of the document can be any JSON value.
+func Parse(data []byte) (*Document, error) {
+	doc := new(Document)
+	if err := doc.Reset(data); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Reset parses data into the document, discarding what it held before
+// and reusing its memory.
+func (doc *Document) Reset(data []byte) error {
+	doc.data = data
+	doc.tape = doc.tape[:0]
+
+	i := skipWhitespace(data, 0)
+	if i >= len(data) {
+		doc.data = nil
+		return syntaxErr(i, endOfDataNoValue, nil)
+	}
+	i, err := doc.parseValue(i, Prefix{})
+	if err != nil {
+		doc.data, doc.tape = nil, doc.tape[:0]
+		return err
+	}
+	if i = skipWhitespace(data, i); i < len(data) {
+		doc.data, doc.tape = nil, doc.tape[:0]
+		return syntaxErr(i, unexpectedDataAfterValue, nil)
+	}
+	return nil
+}
+
+func (doc *Document) parseValue(i int, name Prefix) (int, error) {
+	data := doc.data
+	k := len(doc.tape)
+	doc.tape = append(doc.tape, tapeEntry{name: name})
+
+	et := GuessNextEntityType(data, i)
+	var (
+		to       int
+		children int
+		err      error
+	)
+	switch et {
+	case EntityType_String:
+		var pos Pos
+		pos, err = scanString(data, i)
+		if err != nil {
+			return i, syntaxErr(i, beginStringValueButError, err.(*SyntaxError))
+		}
+		if err = doc.checkUTF8(pos); err != nil {
+			return i, err
+		}
+		to = pos.To
+	case EntityType_Number:
+		_, _, _, to, err = scanNumber(data, i)
+		if err != nil {
+			return i, syntaxErr(i, beginNumberValueButError, err.(*SyntaxError))
+		}
+	case EntityType_Boolean_True, EntityType_Null:
+		to = i + 4
+	case EntityType_Boolean_False:
+		to = i + 5
+	case EntityType_Object:
+		to, children, err = doc.parseObject(i)
+		if err != nil {
+			return i, syntaxErr(i, beginObjectValueButError, err.(*SyntaxError))
+		}
+	case EntityType_Array:
+		to, children, err = doc.parseArray(i)
+		if err != nil {
+			return i, syntaxErr(i, beginArrayValueButError, err.(*SyntaxError))
+		}
+	default:
+		return i, syntaxErr(i, expectValueButNoKnownType, nil)
+	}
+
+	entry := &doc.tape[k]
+	entry.typ = et
+	entry.pos = Pos{From: i, To: to}
+	entry.children = children
+	entry.end = len(doc.tape)
+	return to, nil
+}
+
+// checkUTF8 enforces the UTF8 policy on the key or string at pos.
+func (doc *Document) checkUTF8(pos Pos) error {
+	if doc.UTF8 != UTF8Error {
+		return nil
+	}
+	if i := indexInvalidUTF8(pos.Bytes(doc.data)); i >= 0 {
+		return syntaxErr(pos.From+i, invalidUTF8InString, nil)
+	}
+	return nil
+}
+
+func (doc *Document) parseObject(start int) (int, int, error) {
+	data := doc.data
+	i := skipWhitespace(data, start+1)
+	if i < len(data) && data[i] == '}' {
+		return i + 1, 0, nil
+	}
+	for n := 1; ; n++ {
+		if i >= len(data) {
+			return i, 0, syntaxErr(i, endOfDataNoNamePair, nil)
+		}
+		pfx, j, err := scanPairName(data, i)
+		if err != nil {
+			return i, 0, err
+		}
+		if err = doc.checkUTF8(Pos{From: pfx.from, To: pfx.to}); err != nil {
+			return i, 0, err
+		}
+		if i, err = doc.parseValue(j, pfx); err != nil {
+			return i, 0, err
+		}
+		i = skipWhitespace(data, i)
+		if i >= len(data) {
+			return i, 0, syntaxErr(i, endOfDataNoClosingBracket, nil)
+		}
+		switch data[i] {
+		case '}':
+			return i + 1, n, nil
+		case ',':
+			i = skipWhitespace(data, i+1)
+		default:
+			return i, 0, syntaxErr(i, expectCommaOrClosingBracket, nil)
+		}
+	}
+}
+
+func (doc *Document) parseArray(start int) (int, int, error) {
+	data := doc.data
+	i := skipWhitespace(data, start+1)
+	if i < len(data) && data[i] == ']' {
+		return i + 1, 0, nil
+	}
+	for n := 0; ; n++ {
+		if i >= len(data) {
+			return i, 0, syntaxErr(i, endOfDataNoValue, nil)
+		}
+		var err error
+		if i, err = doc.parseValue(i, newArrayIndexPrefix(n)); err != nil {
+			return i, 0, err
+		}
+		i = skipWhitespace(data, i)
+		if i >= len(data) {
+			return i, 0, syntaxErr(i, endOfDataNoClosingSquareBracket, nil)
+		}
+		switch data[i] {
+		case ']':
+			return i + 1, n + 1, nil
+		case ',':
+			i = skipWhitespace(data, i+1)
+		default:
+			return i, 0, syntaxErr(i, expectCommaOrClosingSquareBracket, nil)
+		}
+	}
+}
+
+// Data the document was parsed from.
+func (doc *Document) Data() []byte { return doc.data }
+
+// Len is the number of values in the document, counting the root and
+// every value nested in it.
+func (doc *Document) Len() int { return len(doc.tape) }
+
+// Root value of the document.
+func (doc *Document) Root() Node { return Node{doc: doc, i: 0} }
+
+// Get the value found by following path from the root of the document.
+// Each element of path is either an object key or an array index.
+func (doc *Document) Get(path ...string) (Node, bool) { return doc.Root().Get(path...) }
+
+// Iterate calls fn on every value nested in the document, depth first
+// and in the order they appear, with the prefixes leading to each
+// value, as ScanObject would. Iteration stops if fn returns false.
+//
+// The prefixes are only valid until fn returns.
+func (doc *Document) Iterate(fn func(prefixes Prefixes, node Node) bool) {
+	if len(doc.tape) == 0 {
+		return
+	}
+	doc.stack = append(doc.stack[:0], 0)
+	doc.prefixes = doc.prefixes[:0]
+	for k := 1; k < len(doc.tape); k++ {
+		for doc.tape[doc.stack[len(doc.stack)-1]].end <= k {
+			doc.stack = doc.stack[:len(doc.stack)-1]
+			doc.prefixes = doc.prefixes[:len(doc.prefixes)-1]
+		}
+		if !fn(doc.prefixes, Node{doc: doc, i: k}) {
+			return
+		}
+		if entry := doc.tape[k]; entry.end > k+1 {
+			doc.stack = append(doc.stack, k)
+			doc.prefixes = append(doc.prefixes, entry.name)
+		}
+	}
+}
+
+// Node is a value of a Document.
+type Node struct {
+	doc *Document
+	i   int
+}
+
+func (n Node) entry() *tapeEntry { return &n.doc.tape[n.i] }
+
+// Type of the value.
+func (n Node) Type() EntityType { return n.entry().typ }
+
+// Name of the value in its parent object or array. The root of the
+// document has no name.
+func (n Node) Name() Prefix { return n.entry().name }
+
+// Pos of the value in the data of the document.
+func (n Node) Pos() Pos { return n.entry().pos }
+
+// Bytes of the value, as found in the data of the document.
+func (n Node) Bytes() []byte { return n.entry().pos.Bytes(n.doc.data) }
+
+// Len is the number of members of an object or elements of an array.
+// It's 0 for any other value.
+func (n Node) Len() int { return n.entry().children }
+
+// Get the value found by following path from this value.
+func (n Node) Get(path ...string) (Node, bool) {
+	for _, elem := range path {
+		child, ok := n.child(elem)
+		if !ok {
+			return Node{}, false
+		}
+		n = child
+	}
+	return n, true
+}
+
+func (n Node) child(elem string) (Node, bool) {
+	switch n.Type() {
+	case EntityType_Object:
+		var found Node
+		ok := false
+		n.Iterate(func(name Prefix, child Node) bool {
+			if name.EqualString(n.doc.data, elem) {
+				// keep going, the last duplicate key wins
+				found, ok = child, true
+			}
+			return true
+		})
+		return found, ok
+	case EntityType_Array:
+		index, ok := parseIndex(elem)
+		if !ok || index >= n.Len() {
+			return Node{}, false
+		}
+		var found Node
+		n.Iterate(func(name Prefix, child Node) bool {
+			if name.Index() == index {
+				found = child
+				return false
+			}
+			return true
+		})
+		return found, true
+	}
+	return Node{}, false
+}
+
+// Iterate calls fn on each member of an object or element of an array,
+// in order. Iteration stops if fn returns false.
+func (n Node) Iterate(fn func(name Prefix, child Node) bool) {
+	tape := n.doc.tape
+	for k := n.i + 1; k < tape[n.i].end; k = tape[k].end {
+		if !fn(tape[k].name, Node{doc: n.doc, i: k}) {
+			return
+		}
+	}
+}
+
+// Int64 value of an integer number.
+func (n Node) Int64() (int64, bool) {
+	if n.Type() != EntityType_Number {
+		return 0, false
+	}
+	_, i64, isInt, _, err := scanNumber(n.doc.data, n.Pos().From)
+	return i64, err == nil && isInt
+}
+
+// Float64 value of a number.
+func (n Node) Float64() (float64, bool) {
+	if n.Type() != EntityType_Number {
+		return 0, false
+	}
+	f64, i64, isInt, _, err := scanNumber(n.doc.data, n.Pos().From)
+	if isInt {
+		f64 = float64(i64)
+	}
+	return f64, err == nil
+}
+
+// Str is the unquoted value of a string, decoded with the UTF8 policy
+// of the document. It refers to the data of the document whenever the
+// string has no escape sequences.
+func (n Node) Str() ([]byte, bool) {
+	if n.Type() != EntityType_String {
+		return nil, false
+	}
+	s, err := Unquoter{InvalidUTF8: n.doc.UTF8}.Unquote(n.Bytes())
+	return s, err == nil
+}
+
+// Bool value of a boolean.
+func (n Node) Bool() (bool, bool) {
+	switch n.Type() {
+	case EntityType_Boolean_True:
+		return true, true
+	case EntityType_Boolean_False:
+		return false, true
+	}
+	return false, false
+}
+
+// IsNull tells if the value is null.
+func (n Node) IsNull() bool { return n.Type() == EntityType_Null }
@@ -0,0 +1,204 @@
+package flatjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDocument(t *testing.T) {
+	data := []byte(`{
+		"key":{"key2": {"deep": 2.5}},
+		"key2":["myname", 42, true, {"is":"antoine"}, null, false],
+		"escaped!": "café",
+		"empty": {}
+	}`)
+	doc, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := 14, doc.Len(); want != got {
+		t.Errorf("want %d values, got %d", want, got)
+	}
+	if want, got := 4, doc.Root().Len(); want != got {
+		t.Errorf("want %d members, got %d", want, got)
+	}
+
+	if n, ok := doc.Get("key", "key2", "deep"); !ok {
+		t.Errorf("want to find key.key2.deep")
+	} else if f, ok := n.Float64(); !ok || f != 2.5 {
+		t.Errorf("want 2.5, got %v (%v)", f, ok)
+	}
+	if n, ok := doc.Get("key2", "1"); !ok {
+		t.Errorf("want to find key2.1")
+	} else if i, ok := n.Int64(); !ok || i != 42 {
+		t.Errorf("want 42, got %v (%v)", i, ok)
+	}
+	for _, index := range []string{"+1", "01", "-0", ""} {
+		if _, ok := doc.Get("key2", index); ok {
+			t.Errorf("want no key2.%s", index)
+		}
+	}
+	if n, ok := doc.Get("key2", "3", "is"); !ok {
+		t.Errorf("want to find key2.3.is")
+	} else if s, ok := n.Str(); !ok || string(s) != "antoine" {
+		t.Errorf("want antoine, got %q (%v)", s, ok)
+	}
+	if n, ok := doc.Get("escaped!"); !ok {
+		t.Errorf("want to find escaped!")
+	} else if s, ok := n.Str(); !ok || string(s) != "café" {
+		t.Errorf("want café, got %q (%v)", s, ok)
+	}
+	if n, ok := doc.Get("key2", "2"); !ok {
+		t.Errorf("want to find key2.2")
+	} else if b, ok := n.Bool(); !ok || !b {
+		t.Errorf("want true, got %v (%v)", b, ok)
+	}
+	if n, ok := doc.Get("key2", "4"); !ok || !n.IsNull() {
+		t.Errorf("want to find null at key2.4")
+	}
+	if n, ok := doc.Get("empty"); !ok || n.Type() != EntityType_Object || n.Len() != 0 {
+		t.Errorf("want to find an empty object")
+	}
+	for _, path := range [][]string{
+		{"nope"},
+		{"key2", "6"},
+		{"key2", "-1"},
+		{"key2", "nope"},
+		{"key", "key2", "deep", "deeper"},
+	} {
+		if _, ok := doc.Get(path...); ok {
+			t.Errorf("want nothing at %q", path)
+		}
+	}
+}
+
+func TestDocumentIterate(t *testing.T) {
+	data := []byte(`{ "a":[{"b":true},{"c":{}}], "d": 1 }`)
+	doc, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []traw
+	doc.Iterate(func(pfx Prefixes, n Node) bool {
+		got = append(got, traw{
+			pfx:  pfx.AsString(data),
+			name: n.Name().String(data),
+			raw:  string(n.Bytes()),
+		})
+		return true
+	})
+	want := []traw{
+		{name: `"a"`, raw: `[{"b":true},{"c":{}}]`},
+		{pfx: "a", name: `0`, raw: `{"b":true}`},
+		{pfx: "a.0", name: `"b"`, raw: `true`},
+		{pfx: "a", name: `1`, raw: `{"c":{}}`},
+		{pfx: "a.1", name: `"c"`, raw: `{}`},
+		{name: `"d"`, raw: `1`},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v", want)
+		t.Errorf(" got %+v", got)
+	}
+
+	var names []string
+	doc.Root().Iterate(func(name Prefix, _ Node) bool {
+		names = append(names, name.String(data))
+		return false
+	})
+	if want := []string{`"a"`}; !reflect.DeepEqual(want, names) {
+		t.Errorf("want %q, got %q", want, names)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Data          string
+		WantErrError  string
+		WantErrOffset int
+	}{
+		{
+			Name:          "empty",
+			Data:          `  `,
+			WantErrError:  endOfDataNoValue,
+			WantErrOffset: 2,
+		},
+		{
+			Name:          "garbage after the document",
+			Data:          `{} {}`,
+			WantErrError:  unexpectedDataAfterValue,
+			WantErrOffset: 3,
+		},
+		{
+			Name:          "missing comma in object",
+			Data:          `{"a":1 "b":2}`,
+			WantErrError:  beginObjectValueButError + ", " + expectCommaOrClosingBracket,
+			WantErrOffset: 0,
+		},
+		{
+			Name:          "missing comma in array",
+			Data:          `[1 2]`,
+			WantErrError:  beginArrayValueButError + ", " + expectCommaOrClosingSquareBracket,
+			WantErrOffset: 0,
+		},
+		{
+			Name:          "trailing comma",
+			Data:          `[1,]`,
+			WantErrError:  beginArrayValueButError + ", " + expectValueButNoKnownType,
+			WantErrOffset: 0,
+		},
+		{
+			Name:          "unclosed object",
+			Data:          `{"a":{"b":1}`,
+			WantErrError:  beginObjectValueButError + ", " + endOfDataNoClosingBracket,
+			WantErrOffset: 0,
+		},
+		{
+			Name:          "bad number",
+			Data:          `{"a":1.}`,
+			WantErrError:  beginObjectValueButError + ", " + beginNumberValueButError + ", " + scanningForFraction + ", " + needAtLeastOneDigit,
+			WantErrOffset: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.Data))
+			gotErr, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("want a syntax error, got %v", err)
+			}
+			if want, got := tt.WantErrOffset, gotErr.Offset; want != got {
+				t.Errorf("want err offset %d, was %d", want, got)
+			}
+			if want, got := tt.WantErrError, gotErr.Error(); want != got {
+				t.Errorf("want error: %q", want)
+				t.Errorf(" got error: %q", got)
+			}
+		})
+	}
+}
+
+func TestDocumentResetDoesntAllocate(t *testing.T) {
+	docs := [][]byte{
+		[]byte(`{"a":[1,2,{"b":[3,4]}],"c":{"d":"e"}}`),
+		[]byte(`{"a":[1,2,{"b":[3,5]}],"c":{"d":"f"}}`),
+	}
+	doc, err := Parse(docs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := 0
+	allocs := testing.AllocsPerRun(100, func() {
+		i++
+		if err := doc.Reset(docs[i%2]); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := doc.Get("a", "2", "b", "1"); !ok {
+			t.Fatal("want to find a.2.b.1")
+		}
+		doc.Iterate(func(Prefixes, Node) bool { return true })
+	})
+	if allocs != 0 {
+		t.Errorf("want no allocations, got %v", allocs)
+	}
+}
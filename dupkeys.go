@@ -0,0 +1,169 @@
+package flatjson
+
+// DuplicateKeyPolicy is what a scan does with keys that appear more
+// than once in an object.
+type DuplicateKeyPolicy uint8
+
+const (
+	// DuplicateKeysAllow reports every member, duplicate or not.
+	DuplicateKeysAllow DuplicateKeyPolicy = iota
+	// DuplicateKeysError fails with a *SyntaxError at the second
+	// occurrence of a key.
+	DuplicateKeysError
+	// DuplicateKeysFirstWins only reports the first member with a key.
+	DuplicateKeysFirstWins
+	// DuplicateKeysLastWins only reports the last member with a key,
+	// which takes a first pass over each object to find it.
+	DuplicateKeysLastWins
+	// DuplicateKeysReport reports every member, and calls
+	// OnDuplicateKey for each key already seen in the object.
+	DuplicateKeysReport
+)
+
+const duplicateKeyInObject = "duplicate key in object"
+
+// smallObjectKeys is how many keys of an object are compared to each
+// other before they are put in a set.
+const smallObjectKeys = 8
+
+// dupKeys finds the duplicate keys of an object. Keys are equal if they
+// are once unquoted.
+type dupKeys struct {
+	data []byte
+	u    Unquoter
+	// keys of the object, the first ones in small and the others in more
+	n     int
+	small [smallObjectKeys]Prefix
+	more  []Prefix
+	// first and last index of each key, once there are many of them
+	first map[string]int
+	last  map[string]int
+}
+
+func (d *dupKeys) reset(data []byte, u Unquoter) {
+	clear(d.first)
+	clear(d.last)
+	*d = dupKeys{data: data, u: u, more: d.more[:0], first: d.first, last: d.last}
+}
+
+func (d *dupKeys) key(k int) Prefix {
+	if k < smallObjectKeys {
+		return d.small[k]
+	}
+	return d.more[k-smallObjectKeys]
+}
+
+func (d *dupKeys) append(key Prefix) {
+	if d.n < smallObjectKeys {
+		d.small[d.n] = key
+	} else {
+		d.more = append(d.more, key)
+	}
+	d.n++
+}
+
+// add adds the key of the next member, and returns the index of the
+// first member that had the same key, or -1.
+func (d *dupKeys) add(key Prefix) int {
+	k := d.n
+	d.append(key)
+	if k < smallObjectKeys {
+		for j := 0; j < k; j++ {
+			if d.equal(d.small[j], key) {
+				return j
+			}
+		}
+		return -1
+	}
+	if len(d.first) == 0 {
+		// the keys so far are known to be unique
+		if d.first == nil {
+			d.first = make(map[string]int, 2*smallObjectKeys)
+		}
+		for j := 0; j < k; j++ {
+			d.first[d.unquote(d.key(j))] = j
+		}
+	}
+	s := d.unquote(key)
+	if j, ok := d.first[s]; ok {
+		return j
+	}
+	d.first[s] = k
+	return -1
+}
+
+// shadowed tells if a member after the k-th one has the same key.
+func (d *dupKeys) shadowed(k int) bool {
+	if d.n <= smallObjectKeys {
+		for j := k + 1; j < d.n; j++ {
+			if d.equal(d.small[k], d.small[j]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(d.last) == 0 {
+		if d.last == nil {
+			d.last = make(map[string]int, d.n)
+		}
+		for j := 0; j < d.n; j++ {
+			d.last[d.unquote(d.key(j))] = j
+		}
+	}
+	return d.last[d.unquote(d.key(k))] != k
+}
+
+func (d *dupKeys) equal(a, b Prefix) bool {
+	rawA, rawB := a.Bytes(d.data), b.Bytes(d.data)
+	if string(rawA) == string(rawB) {
+		return true
+	}
+	var buf [64]byte
+	s, err := d.u.UnquoteTo(buf[:0], rawA)
+	if err != nil {
+		return false
+	}
+	return d.u.Equal(rawB, unsafeBytesToString(s))
+}
+
+// unquote is the key to use in sets. Keys that can't be unquoted are
+// used as they are. Keys refer to the data, and must not outlive it in
+// the sets: reset clears them.
+func (d *dupKeys) unquote(key Prefix) string {
+	s, err := d.u.Unquote(key.Bytes(d.data))
+	if err != nil {
+		return unsafeBytesToString(key.Bytes(d.data))
+	}
+	return unsafeBytesToString(s)
+}
+
+// scanKeys adds the keys of the object at i, for shadowed to tell which
+// ones are duplicated later on. Under limits, it reads no further than
+// the document may go, nor past the keys the object may have.
+func (d *dupKeys) scanKeys(i int, st *scanState) error {
+	data := d.data
+	if st != nil && st.MaxDocumentBytes > 0 && st.start+st.MaxDocumentBytes < len(data) {
+		data = data[:st.start+st.MaxDocumentBytes]
+	}
+	var limit *SyntaxError
+	_, err := scanMembers(data, i, func(m member) bool {
+		if limit = st.member(m.name.from, d.n+1, true); limit != nil {
+			return false
+		}
+		d.append(m.name)
+		return true
+	})
+	if limit != nil {
+		return limit
+	}
+	if serr, ok := err.(*SyntaxError); ok && len(data) < len(d.data) {
+		for serr.SubErr != nil {
+			serr = serr.SubErr
+		}
+		if serr.Offset >= len(data)-1 {
+			// the object goes on past where the document may end
+			return limitErr(len(data), ErrCodeDocumentTooLarge, documentTooLarge)
+		}
+	}
+	return err
+}
@@ -0,0 +1,143 @@
+package flatjson
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDuplicateKeyPolicy(t *testing.T) {
+	many := make([]string, 0, 20)
+	for i := 0; i < 20; i++ {
+		many = append(many, fmt.Sprintf(`"k%d":%d`, i, i))
+	}
+	manyWithDup := `{` + strings.Join(many, ",") + `,"k3":"dup"}`
+
+	tests := []struct {
+		Name   string
+		Data   string
+		Policy DuplicateKeyPolicy
+		Want   string
+	}{
+		{Name: "allow", Data: `{"a":1,"b":2,"a":3}`, Policy: DuplicateKeysAllow, Want: "a=1 b=2 a=3"},
+		{Name: "first wins", Data: `{"a":1,"b":2,"a":3}`, Policy: DuplicateKeysFirstWins, Want: "a=1 b=2"},
+		{Name: "last wins", Data: `{"a":1,"b":2,"a":3}`, Policy: DuplicateKeysLastWins, Want: "b=2 a=3"},
+		{Name: "report", Data: `{"a":1,"b":2,"a":3}`, Policy: DuplicateKeysReport, Want: `a=1 b=2 dup("a"@1,"a"@13) a=3`},
+		{Name: "escaped", Data: `{"a":1,"\u0061":2}`, Policy: DuplicateKeysFirstWins, Want: "a=1"},
+		{Name: "hidden values aren't looked into", Data: `{"a":{"x":1},"a":{"y":2}}`, Policy: DuplicateKeysFirstWins, Want: "a.x=1"},
+		{Name: "last wins nested", Data: `{"a":{"x":1,"x":2},"a":{"y":3,"y":4}}`, Policy: DuplicateKeysLastWins, Want: "a.y=4"},
+		{Name: "per object", Data: `{"a":{"a":1},"b":{"a":2}}`, Policy: DuplicateKeysError, Want: "a.a=1 b.a=2"},
+		{Name: "many keys first wins", Data: manyWithDup, Policy: DuplicateKeysFirstWins, Want: strings.Join(flatMembers(20, -1), " ")},
+		{Name: "many keys last wins", Data: manyWithDup, Policy: DuplicateKeysLastWins, Want: strings.Join(flatMembers(20, 3), " ") + ` k3="dup"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var got []string
+			data := []byte(tt.Data)
+			cb := &Callbacks{
+				MaxDepth:      2,
+				DuplicateKeys: tt.Policy,
+				OnRaw: func(prefixes Prefixes, name Prefix, value Pos) {
+					if b := data[value.From]; b != '{' {
+						got = append(got, Prefixes(append(prefixes, name)).AsString(data)+"="+value.String(data))
+					}
+				},
+				OnDuplicateKey: func(prefixes Prefixes, first, dup Prefix) {
+					got = append(got, fmt.Sprintf("dup(%s@%d,%s@%d)", first.String(data), first.from, dup.String(data), dup.from))
+				},
+			}
+			if _, _, err := ScanObject(data, 0, cb); err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, " ") != tt.Want {
+				t.Errorf("want %s", tt.Want)
+				t.Errorf(" got %s", strings.Join(got, " "))
+			}
+		})
+	}
+}
+
+// flatMembers are the members k0=0, k1=1... of an object, but for skip.
+func flatMembers(n, skip int) []string {
+	var members []string
+	for i := 0; i < n; i++ {
+		if i != skip {
+			members = append(members, fmt.Sprintf("k%d=%d", i, i))
+		}
+	}
+	return members
+}
+
+func TestDuplicateKeyError(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Data          string
+		WantErrOffset int
+	}{
+		{Name: "plain", Data: `{"a":1,"b":2,"a":3}`, WantErrOffset: 13},
+		{Name: "escaped", Data: `{"é":1,"\u00e9":2}`, WantErrOffset: 8},
+		{Name: "nested", Data: `{"x":[{"a":1,"a":2}]}`, WantErrOffset: 13},
+		{Name: "many keys", Data: `{"a":0,"b":1,"c":2,"d":3,"e":4,"f":5,"g":6,"h":7,"i":8,"j":9,"e":10}`, WantErrOffset: 61},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			_, _, err := ScanObject([]byte(tt.Data), 0, &Callbacks{MaxDepth: 3, DuplicateKeys: DuplicateKeysError})
+			serr, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("want a syntax error, got %v", err)
+			}
+			for serr.SubErr != nil {
+				serr = serr.SubErr
+			}
+			if serr.Message != duplicateKeyInObject || serr.Offset != tt.WantErrOffset {
+				t.Errorf("want %q at %d", duplicateKeyInObject, tt.WantErrOffset)
+				t.Errorf(" got %q at %d", serr.Message, serr.Offset)
+			}
+
+			// it's found even where nothing is reported
+			for _, depth := range []int{0, 1} {
+				shallow := &Callbacks{MaxDepth: depth, DuplicateKeys: DuplicateKeysError}
+				if _, _, err := ScanObject([]byte(tt.Data), 0, shallow); err == nil {
+					t.Errorf("max depth %d: want an error", depth)
+				}
+			}
+		})
+	}
+}
+
+func TestDuplicateKeysBelowMaxDepth(t *testing.T) {
+	data := []byte(`{"a":{"b":1,"b":2}}`)
+	var dups int
+	cb := &Callbacks{
+		DuplicateKeys:  DuplicateKeysReport,
+		OnDuplicateKey: func(Prefixes, Prefix, Prefix) { dups++ },
+	}
+	if _, _, err := ScanObject(data, 0, cb); err != nil {
+		t.Fatal(err)
+	}
+	if dups != 1 {
+		t.Errorf("want 1 duplicate, got %d", dups)
+	}
+
+	// hidden values are still checked
+	data = []byte("{\"a\":1,\"a\":{\"b\":\"\xff\"}}")
+	cb = &Callbacks{DuplicateKeys: DuplicateKeysFirstWins, UTF8: UTF8Error}
+	if _, _, err := ScanObject(data, 0, cb); err == nil {
+		t.Errorf("want an error")
+	}
+}
+
+func TestDuplicateKeyAllocs(t *testing.T) {
+	data := []byte(`{"id":1,"name":"a","email":"b","id":2,"ok":true}`)
+	for _, policy := range []DuplicateKeyPolicy{DuplicateKeysFirstWins, DuplicateKeysLastWins, DuplicateKeysReport} {
+		cb := &Callbacks{MaxDepth: 1, DuplicateKeys: policy, OnDuplicateKey: func(Prefixes, Prefix, Prefix) {}}
+		allocs := testing.AllocsPerRun(100, func() {
+			if _, _, err := ScanObject(data, 0, cb); err != nil {
+				t.Fatal(err)
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("policy %d: want no allocation, got %v", policy, allocs)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package flatjson
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrPathNotFound is returned when a path leads nowhere.
+	ErrPathNotFound = errors.New("path not found")
+	// ErrKeyExists is returned when inserting a key that's already in
+	// the object.
+	ErrKeyExists = errors.New("key already exists")
+	// ErrNotContainer is returned when a path leads to a value that
+	// should be an object or an array, but isn't.
+	ErrNotContainer = errors.New("value is neither an object nor an array")
+)
+
+// PathError is an error about a value found, or not found, at a path.
+type PathError struct {
+	Path []string
+	Err  error
+}
+
+func pathErr(path []string, err error) *PathError {
+	return &PathError{Path: path, Err: err}
+}
+
+func (e *PathError) Error() string {
+	return strconv.Quote(strings.Join(e.Path, ".")) + ": " + e.Err.Error()
+}
+
+func (e *PathError) Unwrap() error { return e.Err }
+
+// Set the value at path to raw, which must be a valid JSON value. If
+// the path leads to a missing key of an object, the key is added at the
+// end of the object.
+//
+// The result is a new slice, in which every byte that wasn't changed is
+// copied as-is from data.
+func Set(data []byte, path []string, raw []byte) ([]byte, error) {
+	if err := validateRaw(raw); err != nil {
+		return nil, err
+	}
+	if len(path) == 0 {
+		root, err := locate(data, path)
+		if err != nil {
+			return nil, err
+		}
+		return splice(data, root, raw), nil
+	}
+	parent, members, err := locateMembers(data, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	key := path[len(path)-1]
+	if k := memberIndex(data, members, key); k >= 0 {
+		return splice(data, members[k].value, raw), nil
+	}
+	if data[parent.From] != '{' {
+		return nil, pathErr(path, ErrPathNotFound)
+	}
+	return insertMember(data, parent, members, len(members), key, raw), nil
+}
+
+// Delete the value at path, along with its key if it's in an object.
+// Elements of an array that come after it are shifted down.
+//
+// The result is a new slice, in which every byte that wasn't changed is
+// copied as-is from data.
+func Delete(data []byte, path []string) ([]byte, error) {
+	if len(path) == 0 {
+		return nil, pathErr(path, ErrPathNotFound)
+	}
+	parent, members, err := locateMembers(data, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	k := memberIndex(data, members, path[len(path)-1])
+	if k < 0 {
+		return nil, pathErr(path, ErrPathNotFound)
+	}
+	return deleteMember(data, parent, members, k), nil
+}
+
+// Insert raw, which must be a valid JSON value, into the object or
+// array at path. In an object, raw is added at the end under key, which
+// must not already be in the object. In an array, key is the index raw
+// is inserted at, and must be at most the length of the array.
+//
+// The result is a new slice, in which every byte that wasn't changed is
+// copied as-is from data.
+func Insert(data []byte, path []string, key string, raw []byte) ([]byte, error) {
+	if err := validateRaw(raw); err != nil {
+		return nil, err
+	}
+	parent, members, err := locateMembers(data, path)
+	if err != nil {
+		return nil, err
+	}
+	if data[parent.From] == '{' {
+		if memberIndex(data, members, key) >= 0 {
+			return nil, pathErr(append(path[:len(path):len(path)], key), ErrKeyExists)
+		}
+		return insertMember(data, parent, members, len(members), key, raw), nil
+	}
+	index, ok := parseIndex(key)
+	if !ok || index > len(members) {
+		return nil, pathErr(append(path[:len(path):len(path)], key), ErrPathNotFound)
+	}
+	return insertMember(data, parent, members, index, key, raw), nil
+}
+
+// parseIndex reads an array index in a path: a decimal number without
+// sign or leading zeros.
+func parseIndex(elem string) (int, bool) {
+	if len(elem) == 0 || len(elem) > 1 && elem[0] == '0' {
+		return 0, false
+	}
+	for i := 0; i < len(elem); i++ {
+		if elem[i] < '0' || elem[i] > '9' {
+			return 0, false
+		}
+	}
+	index, err := strconv.Atoi(elem)
+	return index, err == nil
+}
+
+func validateRaw(raw []byte) error {
+	var doc Document
+	return doc.Reset(raw)
+}
+
+// locate finds the position of the value at path. Nothing but
+// whitespace may follow the document.
+func locate(data []byte, path []string) (Pos, error) {
+	i := skipWhitespace(data, 0)
+	_, to, err := skipValue(data, i)
+	if err != nil {
+		return Pos{}, err
+	}
+	if j := skipWhitespace(data, to); j < len(data) {
+		return Pos{}, syntaxErr(j, unexpectedDataAfterValue, nil)
+	}
+	pos := Pos{From: i, To: to}
+	for n, elem := range path {
+		m, found, err := findMember(data, pos.From, elem)
+		if err != nil {
+			return Pos{}, err
+		} else if !found {
+			return Pos{}, pathErr(path[:n+1], ErrPathNotFound)
+		}
+		pos = m.value
+	}
+	return pos, nil
+}
+
+// locateMembers finds the position of the object or array at path, and
+// its members.
+func locateMembers(data []byte, path []string) (Pos, []member, error) {
+	pos, err := locate(data, path)
+	if err != nil {
+		return Pos{}, nil, err
+	}
+	if b := data[pos.From]; b != '{' && b != '[' {
+		return Pos{}, nil, pathErr(path, ErrNotContainer)
+	}
+	var members []member
+	_, err = scanMembers(data, pos.From, func(m member) bool {
+		members = append(members, m)
+		return true
+	})
+	return pos, members, err
+}
+
+// findMember finds the member named elem in the object or array that
+// begins at i. When an object has duplicate keys, the last one wins.
+func findMember(data []byte, i int, elem string) (member, bool, error) {
+	var (
+		found member
+		ok    bool
+		err   error
+	)
+	switch data[i] {
+	case '{':
+		_, err = scanMembers(data, i, func(m member) bool {
+			if m.name.EqualString(data, elem) {
+				found, ok = m, true
+			}
+			return true
+		})
+	case '[':
+		index, isIndex := parseIndex(elem)
+		if !isIndex {
+			return member{}, false, nil
+		}
+		_, err = scanMembers(data, i, func(m member) bool {
+			if m.name.Index() == index {
+				found, ok = m, true
+				return false
+			}
+			return true
+		})
+	}
+	return found, ok, err
+}
+
+// memberIndex finds the member named elem and returns its index, or -1.
+func memberIndex(data []byte, members []member, elem string) int {
+	if len(members) > 0 && members[0].name.IsArrayIndex() {
+		index, ok := parseIndex(elem)
+		if !ok || index >= len(members) {
+			return -1
+		}
+		return index
+	}
+	for k := len(members) - 1; k >= 0; k-- {
+		if members[k].name.EqualString(data, elem) {
+			return k
+		}
+	}
+	return -1
+}
+
+// insertMember inserts raw into the container at pos, so that it becomes
+// its k-th member. The separators are copied from the existing members
+// when possible, to blend in with the formatting of the document.
+func insertMember(data []byte, pos Pos, members []member, k int, key string, raw []byte) []byte {
+	entry := raw
+	if data[pos.From] == '{' {
+		colon := []byte(":")
+		if len(members) > 0 {
+			last := members[len(members)-1]
+			colon = data[last.name.to:last.value.From]
+		}
+		entry = appendQuote(nil, key)
+		entry = append(entry, colon...)
+		entry = append(entry, raw...)
+	}
+	sep := []byte(",")
+	if len(members) > 1 {
+		sep = data[members[0].value.To:members[1].from()]
+	} else if len(members) == 1 {
+		// indent like the only member is
+		sep = append(sep, data[pos.From+1:members[0].from()]...)
+	}
+	switch {
+	case len(members) == 0:
+		return splice(data, Pos{pos.From + 1, pos.To - 1}, entry)
+	case k == len(members):
+		at := members[k-1].value.To
+		return splice(data, Pos{at, at}, sep, entry)
+	default:
+		at := members[k].from()
+		return splice(data, Pos{at, at}, entry, sep)
+	}
+}
+
+// deleteMember removes the k-th member of the container at pos, along
+// with the separator that goes with it.
+func deleteMember(data []byte, pos Pos, members []member, k int) []byte {
+	switch {
+	case len(members) == 1:
+		return splice(data, Pos{pos.From + 1, pos.To - 1})
+	case k < len(members)-1:
+		return splice(data, Pos{members[k].from(), members[k+1].from()})
+	default:
+		return splice(data, Pos{members[k-1].value.To, members[k].value.To})
+	}
+}
+
+// splice returns a copy of data where the bytes at pos are replaced by
+// the concatenation of repl.
+func splice(data []byte, pos Pos, repl ...[]byte) []byte {
+	n := len(data) - (pos.To - pos.From)
+	for _, r := range repl {
+		n += len(r)
+	}
+	out := make([]byte, 0, n)
+	out = append(out, data[:pos.From]...)
+	for _, r := range repl {
+		out = append(out, r...)
+	}
+	return append(out, data[pos.To:]...)
+}
@@ -0,0 +1,285 @@
+package flatjson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEdit(t *testing.T) {
+	pretty := `{
+    "version": 1,
+    "tags": ["a", "b"],
+    "nested": {"x": 1}
+}`
+	tests := []struct {
+		Name string
+		Edit func(data []byte) ([]byte, error)
+		Data string
+
+		Want    string
+		WantErr error
+	}{
+		{
+			Name: "set a top level value",
+			Data: pretty,
+			Edit: func(data []byte) ([]byte, error) { return Set(data, []string{"version"}, []byte(`2`)) },
+			Want: `{
+    "version": 2,
+    "tags": ["a", "b"],
+    "nested": {"x": 1}
+}`,
+		},
+		{
+			Name: "set a nested value",
+			Data: pretty,
+			Edit: func(data []byte) ([]byte, error) {
+				return Set(data, []string{"nested", "x"}, []byte(`{"y": [true]}`))
+			},
+			Want: `{
+    "version": 1,
+    "tags": ["a", "b"],
+    "nested": {"x": {"y": [true]}}
+}`,
+		},
+		{
+			Name: "set an array element",
+			Data: pretty,
+			Edit: func(data []byte) ([]byte, error) { return Set(data, []string{"tags", "1"}, []byte(`"c"`)) },
+			Want: `{
+    "version": 1,
+    "tags": ["a", "c"],
+    "nested": {"x": 1}
+}`,
+		},
+		{
+			Name: "set a missing key adds it",
+			Data: pretty,
+			Edit: func(data []byte) ([]byte, error) {
+				return Set(data, []string{"ingested_at"}, []byte(`"2024-01-01"`))
+			},
+			Want: `{
+    "version": 1,
+    "tags": ["a", "b"],
+    "nested": {"x": 1},
+    "ingested_at": "2024-01-01"
+}`,
+		},
+		{
+			Name: "set the root",
+			Data: ` {"a":1} `,
+			Edit: func(data []byte) ([]byte, error) { return Set(data, nil, []byte(`[]`)) },
+			Want: ` [] `,
+		},
+		{
+			Name: "set a key with escapes",
+			Data: `{"a\"b":1}`,
+			Edit: func(data []byte) ([]byte, error) { return Set(data, []string{`a"b`}, []byte(`2`)) },
+			Want: `{"a\"b":2}`,
+		},
+		{
+			Name: "set a value nested in an array",
+			Data: `{"a":[{"b":1},{"b":2}]}`,
+			Edit: func(data []byte) ([]byte, error) { return Set(data, []string{"a", "1", "b"}, []byte(`3`)) },
+			Want: `{"a":[{"b":1},{"b":3}]}`,
+		},
+		{
+			Name:    "set a missing index",
+			Data:    pretty,
+			Edit:    func(data []byte) ([]byte, error) { return Set(data, []string{"tags", "2"}, []byte(`"c"`)) },
+			WantErr: ErrPathNotFound,
+		},
+		{
+			Name:    "set a signed index",
+			Data:    pretty,
+			Edit:    func(data []byte) ([]byte, error) { return Set(data, []string{"tags", "+1"}, []byte(`"c"`)) },
+			WantErr: ErrPathNotFound,
+		},
+		{
+			Name:    "set a zero-padded index",
+			Data:    pretty,
+			Edit:    func(data []byte) ([]byte, error) { return Set(data, []string{"tags", "01"}, []byte(`"c"`)) },
+			WantErr: ErrPathNotFound,
+		},
+		{
+			Name:    "set an invalid value",
+			Data:    pretty,
+			Edit:    func(data []byte) ([]byte, error) { return Set(data, []string{"version"}, []byte(`2 3`)) },
+			WantErr: &SyntaxError{},
+		},
+		{
+			Name:    "set in a document followed by data",
+			Data:    `{"a":1} junk`,
+			Edit:    func(data []byte) ([]byte, error) { return Set(data, []string{"a"}, []byte(`2`)) },
+			WantErr: &SyntaxError{},
+		},
+		{
+			Name:    "delete in a document followed by data",
+			Data:    `{"a":1} {}`,
+			Edit:    func(data []byte) ([]byte, error) { return Delete(data, []string{"a"}) },
+			WantErr: &SyntaxError{},
+		},
+		{
+			Name:    "set under a scalar",
+			Data:    pretty,
+			Edit:    func(data []byte) ([]byte, error) { return Set(data, []string{"version", "x"}, []byte(`2`)) },
+			WantErr: ErrNotContainer,
+		},
+
+		{
+			Name: "delete the first key",
+			Data: pretty,
+			Edit: func(data []byte) ([]byte, error) { return Delete(data, []string{"version"}) },
+			Want: `{
+    "tags": ["a", "b"],
+    "nested": {"x": 1}
+}`,
+		},
+		{
+			Name: "delete the last key",
+			Data: pretty,
+			Edit: func(data []byte) ([]byte, error) { return Delete(data, []string{"nested"}) },
+			Want: `{
+    "version": 1,
+    "tags": ["a", "b"]
+}`,
+		},
+		{
+			Name: "delete the only key",
+			Data: pretty,
+			Edit: func(data []byte) ([]byte, error) { return Delete(data, []string{"nested", "x"}) },
+			Want: `{
+    "version": 1,
+    "tags": ["a", "b"],
+    "nested": {}
+}`,
+		},
+		{
+			Name: "delete an array element",
+			Data: pretty,
+			Edit: func(data []byte) ([]byte, error) { return Delete(data, []string{"tags", "0"}) },
+			Want: `{
+    "version": 1,
+    "tags": ["b"],
+    "nested": {"x": 1}
+}`,
+		},
+		{
+			Name:    "delete a missing key",
+			Data:    pretty,
+			Edit:    func(data []byte) ([]byte, error) { return Delete(data, []string{"nope"}) },
+			WantErr: ErrPathNotFound,
+		},
+		{
+			Name:    "delete a missing parent",
+			Data:    pretty,
+			Edit:    func(data []byte) ([]byte, error) { return Delete(data, []string{"nope", "nope"}) },
+			WantErr: ErrPathNotFound,
+		},
+
+		{
+			Name: "insert a key",
+			Data: pretty,
+			Edit: func(data []byte) ([]byte, error) { return Insert(data, []string{"nested"}, "y\n", []byte(`2`)) },
+			Want: `{
+    "version": 1,
+    "tags": ["a", "b"],
+    "nested": {"x": 1,"y\n": 2}
+}`,
+		},
+		{
+			Name: "insert after the only key",
+			Data: "{\n  \"a\": 1\n}",
+			Edit: func(data []byte) ([]byte, error) { return Insert(data, nil, "b", []byte(`2`)) },
+			Want: "{\n  \"a\": 1,\n  \"b\": 2\n}",
+		},
+		{
+			Name: "insert in an empty object",
+			Data: `{"a":{ }}`,
+			Edit: func(data []byte) ([]byte, error) { return Insert(data, []string{"a"}, "b", []byte(`null`)) },
+			Want: `{"a":{"b":null}}`,
+		},
+		{
+			Name: "insert at the start of an array",
+			Data: pretty,
+			Edit: func(data []byte) ([]byte, error) { return Insert(data, []string{"tags"}, "0", []byte(`"z"`)) },
+			Want: `{
+    "version": 1,
+    "tags": ["z", "a", "b"],
+    "nested": {"x": 1}
+}`,
+		},
+		{
+			Name: "insert at the end of an array",
+			Data: pretty,
+			Edit: func(data []byte) ([]byte, error) { return Insert(data, []string{"tags"}, "2", []byte(`"z"`)) },
+			Want: `{
+    "version": 1,
+    "tags": ["a", "b", "z"],
+    "nested": {"x": 1}
+}`,
+		},
+		{
+			Name:    "insert at a signed index",
+			Data:    pretty,
+			Edit:    func(data []byte) ([]byte, error) { return Insert(data, []string{"tags"}, "+0", []byte(`"z"`)) },
+			WantErr: ErrPathNotFound,
+		},
+		{
+			Name:    "insert an existing key",
+			Data:    pretty,
+			Edit:    func(data []byte) ([]byte, error) { return Insert(data, nil, "tags", []byte(`1`)) },
+			WantErr: ErrKeyExists,
+		},
+		{
+			Name:    "insert past the end of an array",
+			Data:    pretty,
+			Edit:    func(data []byte) ([]byte, error) { return Insert(data, []string{"tags"}, "3", []byte(`1`)) },
+			WantErr: ErrPathNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			data := []byte(tt.Data)
+			got, err := tt.Edit(data)
+			if string(data) != tt.Data {
+				t.Errorf("input was modified: %s", data)
+			}
+			if tt.WantErr != nil {
+				if se := (*SyntaxError)(nil); errors.As(tt.WantErr, &se) {
+					if !errors.As(err, &se) {
+						t.Errorf("want a syntax error, got %v", err)
+					}
+				} else if !errors.Is(err, tt.WantErr) {
+					t.Errorf("want error %v, got %v", tt.WantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.Want {
+				t.Errorf("want %s", tt.Want)
+				t.Errorf(" got %s", got)
+			}
+		})
+	}
+}
+
+func TestAppendQuote(t *testing.T) {
+	tests := []struct {
+		In   string
+		Want string
+	}{
+		{In: ``, Want: `""`},
+		{In: `hello`, Want: `"hello"`},
+		{In: `a"b\c`, Want: `"a\"b\\c"`},
+		{In: "\b\f\n\r\t\x00\x1f", Want: `"\b\f\n\r\t\u0000\u001f"`},
+		{In: "café 😀", Want: `"café 😀"`},
+		{In: "a\xffb", Want: `"a` + "�" + `b"`},
+	}
+	for _, tt := range tests {
+		if got := string(appendQuote(nil, tt.In)); got != tt.Want {
+			t.Errorf("%q: want %s, got %s", tt.In, tt.Want, got)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package flatjson
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// equalValues tells if the value at ap in a and the value at bp in b are
+// the same JSON value: objects have the same members regardless of
+// their order, numbers have the same numerical value, and strings are
+// the same once unquoted.
+func equalValues(a []byte, ap Pos, b []byte, bp Pos) (bool, error) {
+	at, bt := GuessNextEntityType(a, ap.From), GuessNextEntityType(b, bp.From)
+	if at != bt {
+		return false, nil
+	}
+	switch at {
+	case EntityType_String:
+		ua, err := Unquote(ap.Bytes(a))
+		if err != nil {
+			return false, err
+		}
+		ub, err := Unquote(bp.Bytes(b))
+		if err != nil {
+			return false, err
+		}
+		return bytes.Equal(ua, ub), nil
+
+	case EntityType_Number:
+		return equalNumbers(ap.Bytes(a), bp.Bytes(b)), nil
+
+	case EntityType_Object:
+		am, err := objectMembers(a, ap.From)
+		if err != nil {
+			return false, err
+		}
+		bm, err := objectMembers(b, bp.From)
+		if err != nil {
+			return false, err
+		}
+		if len(am) != len(bm) {
+			return false, nil
+		}
+		for key, av := range am {
+			bv, ok := bm[key]
+			if !ok {
+				return false, nil
+			}
+			if eq, err := equalValues(a, av, b, bv); err != nil || !eq {
+				return false, err
+			}
+		}
+		return true, nil
+
+	case EntityType_Array:
+		ae, err := arrayElements(a, ap.From)
+		if err != nil {
+			return false, err
+		}
+		be, err := arrayElements(b, bp.From)
+		if err != nil {
+			return false, err
+		}
+		if len(ae) != len(be) {
+			return false, nil
+		}
+		for k := range ae {
+			if eq, err := equalValues(a, ae[k], b, be[k]); err != nil || !eq {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	return true, nil
+}
+
+// equalNumbers tells if two JSON numbers have the same value.
+func equalNumbers(a, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	fa, errA := strconv.ParseFloat(unsafeBytesToString(a), 64)
+	fb, errB := strconv.ParseFloat(unsafeBytesToString(b), 64)
+	return errA == nil && errB == nil && fa == fb
+}
+
+// objectMembers maps the unquoted keys of the object starting at i to
+// the position of their values. The last of duplicate keys wins.
+func objectMembers(data []byte, i int) (map[string]Pos, error) {
+	members := make(map[string]Pos)
+	var err error
+	_, scanErr := scanMembers(data, i, func(m member) bool {
+		var key []byte
+		if key, err = Unquote(m.name.Bytes(data)); err != nil {
+			return false
+		}
+		members[string(key)] = m.value
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members, scanErr
+}
+
+// arrayElements returns the position of each element of the array
+// starting at i.
+func arrayElements(data []byte, i int) ([]Pos, error) {
+	var elems []Pos
+	_, err := scanMembers(data, i, func(m member) bool {
+		elems = append(elems, m.value)
+		return true
+	})
+	return elems, err
+}
@@ -0,0 +1,174 @@
+package flatjson
+
+import (
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// EqualString tells if the object key is s once unquoted, without
+// unquoting it. Array indices are never equal to a string.
+func (pfx Prefix) EqualString(data []byte, s string) bool {
+	return pfx.IsObjectKey() && Unquoter{}.equal(pfx.Bytes(data), s, false)
+}
+
+// EqualFoldString is EqualString under simple Unicode case folding,
+// like strings.EqualFold.
+func (pfx Prefix) EqualFoldString(data []byte, s string) bool {
+	return pfx.IsObjectKey() && Unquoter{}.equal(pfx.Bytes(data), s, true)
+}
+
+// Equal tells if the string value is s once unquoted, without
+// unquoting it.
+func (s String) Equal(data []byte, str string) bool {
+	return Unquoter{}.equal(s.Value.Bytes(data), str, false)
+}
+
+// EqualFold is Equal under simple Unicode case folding, like
+// strings.EqualFold.
+func (s String) EqualFold(data []byte, str string) bool {
+	return Unquoter{}.equal(s.Value.Bytes(data), str, true)
+}
+
+// Equal tells if the quoted string raw is s once unquoted, without
+// unquoting it. Strings that can't be unquoted are never equal.
+func (u Unquoter) Equal(raw []byte, s string) bool {
+	return u.equal(raw, s, false)
+}
+
+// EqualFold is Equal under simple Unicode case folding, like
+// strings.EqualFold.
+func (u Unquoter) EqualFold(raw []byte, s string) bool {
+	return u.equal(raw, s, true)
+}
+
+// equal tells if the quoted string raw is s once unquoted, decoding
+// escapes as it compares.
+func (u Unquoter) equal(raw []byte, s string, fold bool) bool {
+	n := len(raw)
+	if n < 2 || raw[0] != '"' || raw[n-1] != '"' {
+		return false
+	}
+	end := n - 1
+	i, j := 1, 0
+	for i < end {
+		c := raw[i]
+		if c >= ' ' && c < utf8.RuneSelf && c != '\\' && c != '"' {
+			if j == len(s) {
+				return false
+			}
+			if b := s[j]; b == c || (fold && equalFoldASCII(b, c)) {
+				i, j = i+1, j+1
+				continue
+			} else if !fold || b < utf8.RuneSelf {
+				return false
+			}
+			// some characters, like the Kelvin sign, fold to ASCII
+		}
+		if c >= utf8.RuneSelf && u.InvalidUTF8 != UTF8Replace {
+			if r, size := utf8.DecodeRune(raw[i:end]); r == utf8.RuneError && size == 1 {
+				if u.InvalidUTF8 == UTF8Error || j == len(s) || s[j] != c {
+					return false
+				}
+				i, j = i+1, j+1
+				continue
+			}
+		}
+		r, next := u.quotedRune(raw[:end], i)
+		if next < 0 {
+			return false
+		}
+		i = next
+		if fold {
+			sr, size := utf8.DecodeRuneInString(s[j:])
+			if size == 0 || !equalFoldRune(r, sr) {
+				return false
+			}
+			j += size
+			continue
+		}
+		var buf [utf8.UTFMax]byte
+		size := utf8.EncodeRune(buf[:], r)
+		if len(s)-j < size || s[j:j+size] != string(buf[:size]) {
+			return false
+		}
+		j += size
+	}
+	return j == len(s)
+}
+
+// quotedRune decodes the character at i of the content of a quoted
+// string, and returns where the next one begins, or -1 if it's invalid.
+func (u Unquoter) quotedRune(raw []byte, i int) (rune, int) {
+	c := raw[i]
+	switch {
+	case c == '\\':
+		if i+1 == len(raw) {
+			return 0, -1
+		}
+		switch raw[i+1] {
+		case '"', '\\', '/':
+			return rune(raw[i+1]), i + 2
+		case 'b':
+			return '\b', i + 2
+		case 'f':
+			return '\f', i + 2
+		case 'n':
+			return '\n', i + 2
+		case 'r':
+			return '\r', i + 2
+		case 't':
+			return '\t', i + 2
+		case 'u':
+			r, ok := decodeHex4(raw[i+2:])
+			if !ok {
+				return 0, -1
+			}
+			if !utf16.IsSurrogate(r) {
+				return r, i + 6
+			}
+			if r2, ok := decodeEscapedRune(raw[i+6:]); ok {
+				if pair := utf16.DecodeRune(r, r2); pair != utf8.RuneError {
+					return pair, i + 12
+				}
+			}
+			if u.LoneSurrogates == SurrogateError {
+				return 0, -1
+			}
+			return utf8.RuneError, i + 6
+		}
+		return 0, -1
+	case c == '"' || c < ' ':
+		return 0, -1
+	case c < utf8.RuneSelf:
+		return rune(c), i + 1
+	}
+	r, size := utf8.DecodeRune(raw[i:])
+	return r, i + size
+}
+
+func equalFoldASCII(a, b byte) bool {
+	if a|0x20 != b|0x20 {
+		return false
+	}
+	a |= 0x20
+	return a >= 'a' && a <= 'z'
+}
+
+// equalFoldRune is the rune comparison of strings.EqualFold.
+func equalFoldRune(a, b rune) bool {
+	if a == b {
+		return true
+	}
+	if b < a {
+		a, b = b, a
+	}
+	if b < utf8.RuneSelf {
+		return 'A' <= a && a <= 'Z' && b == a+'a'-'A'
+	}
+	r := unicode.SimpleFold(a)
+	for r != a && r < b {
+		r = unicode.SimpleFold(r)
+	}
+	return r == b
+}
@@ -0,0 +1,89 @@
+package flatjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEqualString(t *testing.T) {
+	tests := []struct {
+		Raw      string
+		S        string
+		Want     bool
+		WantFold bool
+	}{
+		{Raw: `"content-type"`, S: "content-type", Want: true, WantFold: true},
+		{Raw: `"Content-Type"`, S: "content-type", Want: false, WantFold: true},
+		{Raw: `"content-type"`, S: "content-typ", Want: false, WantFold: false},
+		{Raw: `"content-typ"`, S: "content-type", Want: false, WantFold: false},
+		{Raw: `""`, S: "", Want: true, WantFold: true},
+		{Raw: `"a\"b\\c\/d\n"`, S: "a\"b\\c/d\n", Want: true, WantFold: true},
+		{Raw: `"😀"`, S: "😀", Want: true, WantFold: true},
+		{Raw: `"\ud83d\ude00"`, S: "😀", Want: true, WantFold: true},
+		{Raw: `"\u00C9"`, S: "é", Want: false, WantFold: true},
+		{Raw: `"\ud83d"`, S: "�", Want: true, WantFold: true},
+		{Raw: `"é"`, S: "É", Want: false, WantFold: true},
+		{Raw: `"é"`, S: "é", Want: true, WantFold: true},
+		{Raw: `"k"`, S: "K", Want: false, WantFold: true},
+		{Raw: `"@"`, S: "`", Want: false, WantFold: false},
+		{Raw: "\"a\xffb\"", S: "a�b", Want: true, WantFold: true},
+		{Raw: `"\x41"`, S: "A", Want: false, WantFold: false},
+		{Raw: "\"a\tb\"", S: "a\tb", Want: false, WantFold: false},
+		{Raw: `"a"b"`, S: `a"b`, Want: false, WantFold: false},
+		{Raw: `"\u12"`, S: "\u0012", Want: false, WantFold: false},
+		{Raw: `abc`, S: "b", Want: false, WantFold: false},
+	}
+	for _, tt := range tests {
+		data := []byte(`{` + tt.Raw + `:` + tt.Raw + `}`)
+		name := newObjectKeyPrefix(1, 1+len(tt.Raw))
+		value := String{Name: name, Value: Pos{From: 2 + len(tt.Raw), To: 2 + 2*len(tt.Raw)}}
+
+		if got := name.EqualString(data, tt.S); got != tt.Want {
+			t.Errorf("%s EqualString %q: want %v, got %v", tt.Raw, tt.S, tt.Want, got)
+		}
+		if got := value.Equal(data, tt.S); got != tt.Want {
+			t.Errorf("%s Equal %q: want %v, got %v", tt.Raw, tt.S, tt.Want, got)
+		}
+		if got := name.EqualFoldString(data, tt.S); got != tt.WantFold {
+			t.Errorf("%s EqualFoldString %q: want %v, got %v", tt.Raw, tt.S, tt.WantFold, got)
+		}
+		if got := value.EqualFold(data, tt.S); got != tt.WantFold {
+			t.Errorf("%s EqualFold %q: want %v, got %v", tt.Raw, tt.S, tt.WantFold, got)
+		}
+	}
+
+	if newArrayIndexPrefix(0).EqualString(nil, "0") {
+		t.Errorf("array index equal to a string")
+	}
+}
+
+func TestEqualStringAllocs(t *testing.T) {
+	data := []byte(`{"Content-Type":"text"}`)
+	name := newObjectKeyPrefix(1, 15)
+	allocs := testing.AllocsPerRun(100, func() {
+		if !name.EqualFoldString(data, "content-type") || !name.EqualString(data, "Content-Type") {
+			t.Fatal("want equal")
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("want no allocation, got %v", allocs)
+	}
+}
+
+func FuzzEqualString(f *testing.F) {
+	f.Add([]byte(`"content-type"`), "content-type")
+	f.Add([]byte(`"😀\ud83d"`), "😀")
+	f.Add([]byte(`"K"`), "K")
+	f.Fuzz(func(t *testing.T, raw []byte, s string) {
+		value := String{Value: Pos{From: 0, To: len(raw)}}
+		unquoted, err := Unquote(raw)
+		want := err == nil && string(unquoted) == s
+		if got := value.Equal(raw, s); got != want {
+			t.Errorf("%q Equal %q: want %v, got %v", raw, s, want, got)
+		}
+		wantFold := err == nil && strings.EqualFold(string(unquoted), s)
+		if got := value.EqualFold(raw, s); got != wantFold {
+			t.Errorf("%q EqualFold %q: want %v, got %v", raw, s, wantFold, got)
+		}
+	})
+}
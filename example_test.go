@@ -69,3 +69,34 @@ func ExampleScanObject() {
 	// key="le"
 	// value="\"monde\""
 }
+
+func ExampleScanObject_onValue() {
+	data := []byte(`{
+		"hello":["world", 42],
+		"bonjour": {"le": "monde"}
+	}`)
+
+	flatjson.ScanObject(data, 0, &flatjson.Callbacks{
+		MaxDepth: 99,
+		OnValue: func(prefixes flatjson.Prefixes, val flatjson.Value) {
+			path := append(prefixes, val.Name).AsString(data)
+			switch val.Kind {
+			case flatjson.EntityType_String:
+				s, _ := val.Str(data)
+				fmt.Printf("%s=%q\n", path, s)
+			case flatjson.EntityType_Number:
+				f64, _ := val.Float64()
+				fmt.Printf("%s=%v\n", path, f64)
+			default:
+				fmt.Printf("%s is %v\n", path, val.Kind)
+			}
+		},
+	})
+
+	// Output:
+	// hello.0="world"
+	// hello.1=42
+	// hello is array
+	// bonjour.le="monde"
+	// bonjour is object
+}
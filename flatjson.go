@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"math"
 	"strconv"
-	"strings"
 )
 
 type EntityType uint8
@@ -58,16 +57,22 @@ func GuessNextEntityType(data []byte, i int) EntityType {
 type SyntaxError struct {
 	Offset  int
 	Message string
+	// Code is the kind of problem, the same as that of SubErr if any.
+	Code ErrorCode
 
 	SubErr *SyntaxError
 }
 
 func syntaxErr(offset int, msg string, suberr *SyntaxError) *SyntaxError {
-	return &SyntaxError{
+	err := &SyntaxError{
 		Offset:  offset,
 		Message: msg,
 		SubErr:  suberr,
 	}
+	if suberr != nil {
+		err.Code = suberr.Code
+	}
+	return err
 }
 
 func (s *SyntaxError) Error() string {
@@ -88,25 +93,11 @@ func (p Pos) String(data []byte) string { return string(p.Bytes(data)) }
 type Prefixes []Prefix
 
 func (pfxs Prefixes) AsString(data []byte) string {
-	if len(pfxs) == 0 {
-		return ""
-	}
-	bd := strings.Builder{}
-	for i, pfx := range pfxs {
-		if i != 0 {
-			bd.WriteRune('.')
-		}
-		if pfx.IsArrayIndex() {
-			bd.WriteString(strconv.Itoa(pfx.Index()))
-		} else {
-			s, err := Unquote(pfx.Bytes(data))
-			if err != nil {
-				panic(fmt.Sprintf("prefix %q: %v", pfx.String(data), err))
-			}
-			bd.Write(s)
-		}
+	s, err := Unquoter{}.AsString(data, pfxs)
+	if err != nil {
+		panic(fmt.Sprintf("prefixes: %v", err))
 	}
-	return bd.String()
+	return s
 }
 
 type Prefix struct {
@@ -187,6 +178,143 @@ type Callbacks struct {
 	onArray  arrayDec
 
 	OnRaw func(prefixes Prefixes, name Prefix, value Pos)
+
+	// OnValue is called for values of any kind, objects and arrays
+	// included, once their end is found: after what they hold.
+	OnValue func(prefixes Prefixes, val Value)
+
+	// The callbacks ending with Err are those above that can fail: the
+	// scan then stops with a *CallbackError wrapping their error.
+	OnFloatErr   func(prefixes Prefixes, val Float) error
+	OnIntegerErr func(prefixes Prefixes, val Integer) error
+	OnStringErr  func(prefixes Prefixes, val String) error
+	OnBooleanErr func(prefixes Prefixes, val Bool) error
+	OnNullErr    func(prefixes Prefixes, val Null) error
+	OnValueErr   func(prefixes Prefixes, val Value) error
+
+	// Limits bound what the scan accepts.
+	Limits Limits
+
+	// UTF8 is what to do with keys and strings that aren't valid UTF-8.
+	// With UTF8Error, the scan fails at the first invalid byte. Otherwise
+	// they are reported as they are, for an Unquoter with the same
+	// policy to decode.
+	UTF8 UTF8Policy
+
+	// DuplicateKeys is what to do with keys that appear more than once
+	// in an object. Members that aren't reported are only looked into
+	// to enforce Limits and UTF8Error.
+	DuplicateKeys DuplicateKeyPolicy
+	// OnDuplicateKey is called with DuplicateKeysReport when the key dup
+	// of an object was already seen as first.
+	OnDuplicateKey func(prefixes Prefixes, first, dup Prefix)
+
+	// Index, when set, must be the structural index of the data being
+	// scanned. Objects and arrays nested deeper than MaxDepth, which are
+	// only parsed to enforce Limits, UTF8Error, DuplicateKeysError and
+	// DuplicateKeysReport, are otherwise jumped
+	// over instead of being scanned for their closing bracket.
+	Index *Index
+}
+
+// Unquoter returns an Unquoter that decodes keys and strings according
+// to the UTF8 policy of the scan.
+func (cb *Callbacks) Unquoter() Unquoter {
+	if cb == nil {
+		return Unquoter{}
+	}
+	return Unquoter{InvalidUTF8: cb.UTF8}
+}
+
+// checkUTF8 enforces the UTF8 policy on the key or string at pos.
+func (cb *Callbacks) checkUTF8(data []byte, pos Pos) error {
+	if cb == nil || cb.UTF8 != UTF8Error {
+		return nil
+	}
+	if i := indexInvalidUTF8(data[pos.From:pos.To]); i >= 0 {
+		return syntaxErr(pos.From+i, invalidUTF8InString, nil)
+	}
+	return nil
+}
+
+// checkDuplicate enforces the DuplicateKeys policy on the key of the
+// member-th member of an object, and tells if the member is hidden.
+func (cb *Callbacks) checkDuplicate(dups *dupKeys, prefixes Prefixes, key Prefix, member int) (bool, error) {
+	if cb.DuplicateKeys == DuplicateKeysLastWins {
+		return dups.shadowed(member), nil
+	}
+	first := dups.add(key)
+	if first < 0 {
+		return false, nil
+	}
+	switch cb.DuplicateKeys {
+	case DuplicateKeysError:
+		return false, syntaxErr(key.from, duplicateKeyInObject, nil)
+	case DuplicateKeysFirstWins:
+		return true, nil
+	case DuplicateKeysReport:
+		if cb.OnDuplicateKey != nil {
+			cb.OnDuplicateKey(prefixes, dups.key(first), key)
+		}
+	}
+	return false, nil
+}
+
+// skipHidden finds where the value at i of a hidden member ends. Under
+// limits, the value is scanned for them without being reported.
+func (cb *Callbacks) skipHidden(data []byte, i int, prefixes []Prefix, pfx Prefix, st *scanState) (Pos, error) {
+	if st == nil || st.Limits == (Limits{}) {
+		_, to, err := skipValue(data, i)
+		if err != nil {
+			return Pos{}, err
+		}
+		// bytes that aren't ASCII can only be in its keys and strings
+		return Pos{i, to}, cb.checkUTF8(data, Pos{i, to})
+	}
+	quiet := &Callbacks{MaxDepth: -1, Limits: cb.Limits, UTF8: cb.UTF8}
+	switch GuessNextEntityType(data, i) {
+	case EntityType_Object:
+		pos, _, err := scanObject(data, i, st.push(prefixes, pfx), quiet, st)
+		if err != nil {
+			return Pos{}, nestedErr(i, beginObjectValueButError, err)
+		}
+		return pos, nil
+	case EntityType_Array:
+		pos, _, err := scanArray(data, i, st.push(prefixes, pfx), quiet, st)
+		if err != nil {
+			return Pos{}, nestedErr(i, beginArrayValueButError, err)
+		}
+		return pos, nil
+	case EntityType_String:
+		if err := st.str(data, i); err != nil {
+			return Pos{}, err
+		}
+	case EntityType_Number:
+		if err := st.number(data, i); err != nil {
+			return Pos{}, err
+		}
+	}
+	_, to, err := skipValue(data, i)
+	if err != nil {
+		return Pos{}, err
+	}
+	return Pos{i, to}, cb.checkUTF8(data, Pos{i, to})
+}
+
+// reports tells if values at the given depth are reported.
+func (cb *Callbacks) reports(depth int) bool {
+	return cb != nil && cb.MaxDepth >= depth
+}
+
+// skipsDepth tells if nothing at the given depth will be reported nor
+// checked.
+func (cb *Callbacks) skipsDepth(depth int) bool {
+	if cb == nil || cb.MaxDepth >= depth {
+		return false
+	}
+	checked := cb.Limits != (Limits{}) || cb.UTF8 == UTF8Error ||
+		cb.DuplicateKeys == DuplicateKeysError || cb.DuplicateKeys == DuplicateKeysReport
+	return !checked
 }
 
 const (
@@ -212,10 +340,11 @@ const (
 // ScanObject according to the spec at http://www.json.org/
 // but ignoring nested objects and arrays
 func ScanObject(data []byte, from int, cb *Callbacks) (pos Pos, found bool, err error) {
-	return scanObject(data, from, nil, cb)
+	var st scanState
+	return scanObject(data, from, nil, cb, st.init(cb, from, nil))
 }
 
-func scanObject(data []byte, from int, prefixes []Prefix, cb *Callbacks) (pos Pos, found bool, _ error) {
+func scanObject(data []byte, from int, prefixes []Prefix, cb *Callbacks, st *scanState) (pos Pos, found bool, _ error) {
 	if from < 0 {
 		panic(fmt.Sprintf("negative starting index %d", from))
 	} else if len(data) == 0 {
@@ -231,8 +360,41 @@ func scanObject(data []byte, from int, prefixes []Prefix, cb *Callbacks) (pos Po
 	if len(data) == 0 || data[start] != '{' {
 		return pos, false, syntaxErr(start, noOpeningBracketFound, nil)
 	}
+	if err := st.enter(start, len(prefixes)); err != nil {
+		return pos, false, err
+	}
+	if len(prefixes) == 0 {
+		if err := st.value(start); err != nil {
+			return pos, false, err
+		}
+	}
+	if cb.skipsDepth(len(prefixes)) {
+		// nothing in here will be reported, only find where it ends
+		to, ok := cb.Index.skip(data, start)
+		if !ok {
+			var err error
+			if to, err = skipContainer(data, start); err != nil {
+				return pos, false, err
+			}
+		}
+		return Pos{start, to}, true, nil
+	}
+	var dups *dupKeys
+	if cb != nil && cb.DuplicateKeys != DuplicateKeysAllow {
+		if st != nil && st.reuse {
+			dups = st.dupKeys(len(prefixes))
+		} else {
+			dups = new(dupKeys)
+		}
+		dups.reset(data, cb.Unquoter())
+		if cb.DuplicateKeys == DuplicateKeysLastWins {
+			if err := dups.scanKeys(start, st); err != nil {
+				return pos, false, err
+			}
+		}
+	}
 	i := start + 1
-	for ; i < len(data); i++ {
+	for member := 0; i < len(data); i, member = i+1, member+1 {
 
 		i = skipWhitespace(data, i)
 		if i >= len(data) {
@@ -240,36 +402,74 @@ func scanObject(data []byte, from int, prefixes []Prefix, cb *Callbacks) (pos Po
 		}
 
 		if data[i] == '}' {
+			if err := st.offset(i + 1); err != nil {
+				return pos, false, err
+			}
 			return Pos{start, i + 1}, true, nil
 		}
 
 		// scan the name
+		if err := st.str(data, i); err != nil {
+			return pos, false, err
+		}
 		pfx, j, err := scanPairName(data, i)
 		if err != nil {
 			return Pos{From: pfx.from, To: pfx.to}, false, err
 		}
+		if err := cb.checkUTF8(data, Pos{From: pfx.from, To: pfx.to}); err != nil {
+			return pos, false, err
+		}
+		if err := st.member(pfx.from, member+1, true); err != nil {
+			return pos, false, err
+		}
+		if err := st.value(j); err != nil {
+			return pos, false, err
+		}
 		i = j
 
+		// a member with a duplicate key may not be reported
+		var hidden bool
+		if dups != nil {
+			if hidden, err = cb.checkDuplicate(dups, prefixes, pfx, member); err != nil {
+				return pos, false, err
+			}
+		}
+
 		// decide if the value is a number, string, object, array, bool or null
 		et := GuessNextEntityType(data, i)
 
-		var valPos Pos
-		if et == EntityType_String { // strings
+		var (
+			valPos Pos
+			num    numberValue
+		)
+		if hidden {
+			if valPos, err = cb.skipHidden(data, i, prefixes, pfx, st); err != nil {
+				return pos, false, err
+			}
+			i = valPos.To
+
+		} else if et == EntityType_String { // strings
+			if err := st.str(data, i); err != nil {
+				return pos, false, err
+			}
 			valPos, err = scanString(data, i)
 			if err != nil {
 				return pos, false, syntaxErr(i, beginStringValueButError, err.(*SyntaxError))
 			}
+			if err := cb.checkUTF8(data, valPos); err != nil {
+				return pos, false, err
+			}
 
-			if cb != nil && cb.OnString != nil && cb.MaxDepth >= len(prefixes) {
+			if cb.reports(len(prefixes)) && cb.OnString != nil {
 				cb.OnString(prefixes, String{Name: pfx, Value: valPos})
 			}
 			i = valPos.To
 
 		} else if et == EntityType_Object { // objects
 			// careful not to shadow `valPos`, we need it to be updated
-			valPos, found, err = scanObject(data, i, append(prefixes, pfx), cb) // TODO: fix recursion
+			valPos, found, err = scanObject(data, i, st.push(prefixes, pfx), cb, st) // TODO: fix recursion
 			if err != nil {
-				return Pos{}, found, syntaxErr(i, beginObjectValueButError, err.(*SyntaxError))
+				return Pos{}, found, nestedErr(i, beginObjectValueButError, err)
 			} else if !found {
 				return Pos{}, found, syntaxErr(i, expectValueButNoKnownType, nil)
 			}
@@ -277,28 +477,33 @@ func scanObject(data []byte, from int, prefixes []Prefix, cb *Callbacks) (pos Po
 
 		} else if et == EntityType_Array { // arrays
 			// careful not to shadow `valPos`, we need it to be updated
-			valPos, found, err = scanArray(data, i, append(prefixes, pfx), cb) // TODO: fix recursion
+			valPos, found, err = scanArray(data, i, st.push(prefixes, pfx), cb, st) // TODO: fix recursion
 			if err != nil {
-				return Pos{}, found, syntaxErr(i, beginArrayValueButError, err.(*SyntaxError))
+				return Pos{}, found, nestedErr(i, beginArrayValueButError, err)
 			} else if !found {
 				return Pos{}, found, syntaxErr(i, expectValueButNoKnownType, nil)
 			}
 			i = valPos.To
 
 		} else if et == EntityType_Number { // numbers
+			if err := st.number(data, i); err != nil {
+				return pos, false, err
+			}
 			f64, i64, isInt, j, err := scanNumber(data, i)
 			if err != nil {
 				return pos, false, syntaxErr(i, beginNumberValueButError, err.(*SyntaxError))
 			}
 			valPos = Pos{From: i, To: j}
+			num = numberValue{f64: f64, i64: i64, isInt: isInt}
 			j = skipWhitespace(data, j)
 			if j < len(data) && data[j] != ',' && data[j] != '}' {
 				return pos, false, syntaxErr(i, malformedNumber, nil)
 			}
-			if cb != nil && cb.MaxDepth >= len(prefixes) {
-				if isInt && cb.OnInteger != nil {
+			if cb.reports(len(prefixes)) {
+				switch {
+				case isInt && cb.OnInteger != nil:
 					cb.OnInteger(prefixes, Integer{Name: pfx, Value: i64})
-				} else if cb.OnFloat != nil {
+				case cb.OnFloat != nil:
 					cb.OnFloat(prefixes, Float{Name: pfx, Value: f64})
 				}
 			}
@@ -307,7 +512,7 @@ func scanObject(data []byte, from int, prefixes []Prefix, cb *Callbacks) (pos Po
 		} else if et == EntityType_Boolean_True {
 			j = i + 4
 			valPos = Pos{From: i, To: j}
-			if cb != nil && cb.OnBoolean != nil && cb.MaxDepth >= len(prefixes) {
+			if cb.reports(len(prefixes)) && cb.OnBoolean != nil {
 				cb.OnBoolean(prefixes, Bool{Name: pfx, Value: true})
 			}
 			i = j
@@ -315,14 +520,14 @@ func scanObject(data []byte, from int, prefixes []Prefix, cb *Callbacks) (pos Po
 		} else if et == EntityType_Boolean_False {
 			j = i + 5
 			valPos = Pos{From: i, To: j}
-			if cb != nil && cb.OnBoolean != nil && cb.MaxDepth >= len(prefixes) {
+			if cb.reports(len(prefixes)) && cb.OnBoolean != nil {
 				cb.OnBoolean(prefixes, Bool{Name: pfx, Value: false})
 			}
 			i = j
 
 		} else if et == EntityType_Null {
 			j = i + 4
-			if cb != nil && cb.OnNull != nil && cb.MaxDepth >= len(prefixes) {
+			if cb.reports(len(prefixes)) && cb.OnNull != nil {
 				cb.OnNull(prefixes, Null{Name: pfx})
 			}
 			valPos = Pos{From: i, To: j}
@@ -331,9 +536,17 @@ func scanObject(data []byte, from int, prefixes []Prefix, cb *Callbacks) (pos Po
 		} else {
 			return pos, false, syntaxErr(i, expectValueButNoKnownType, nil)
 		}
-		if cb != nil && cb.OnRaw != nil && cb.MaxDepth >= len(prefixes) {
+		if !hidden && cb != nil && cb.OnRaw != nil && cb.MaxDepth >= len(prefixes) {
 			cb.OnRaw(prefixes, pfx, valPos)
 		}
+		if !hidden && cb != nil && cb.OnValue != nil && cb.MaxDepth >= len(prefixes) {
+			cb.OnValue(prefixes, Value{Kind: et, Name: pfx, Pos: valPos, num: num, u: cb.Unquoter()})
+		}
+		if !hidden && cb.reports(len(prefixes)) && cb.failable() {
+			if err := cb.reportErr(prefixes, Value{Kind: et, Name: pfx, Pos: valPos, num: num, u: cb.Unquoter()}); err != nil {
+				return pos, false, err
+			}
+		}
 
 		i = skipWhitespace(data, i)
 		if i < len(data) {
@@ -341,6 +554,9 @@ func scanObject(data []byte, from int, prefixes []Prefix, cb *Callbacks) (pos Po
 				// more values to come
 				// TODO(antoine): be kind and accept trailing commas
 			} else if data[i] == '}' {
+				if err := st.offset(i + 1); err != nil {
+					return pos, false, err
+				}
 				return Pos{start, i + 1}, true, nil
 			}
 		}
@@ -357,6 +573,45 @@ const (
 // is one-past where it last found a string component.
 // It does not deal with whitespace.
 func scanString(data []byte, i int) (Pos, error) {
+	if !useSWAR {
+		return scanStringBytewise(data, i)
+	}
+	from := i
+	to := i + 1
+	for {
+		// jump over plain string content, 8 bytes at a time
+		to = indexStringSpecial(data, to)
+		if to >= len(data) {
+			break
+		}
+		b := data[to]
+		if b == '"' {
+			to++
+			return Pos{From: from, To: to}, nil
+		}
+		if b == '\\' {
+			to++
+			// skip the 4 next hex digits
+			if to < len(data) && data[to] == 'u' {
+				if len(data) < to+5 {
+					return Pos{}, syntaxErr(to, reachedEndScanningCharacters, nil)
+				}
+				for j, b := range data[to+1 : to+5] {
+					if !isHexDigit(b) {
+						return Pos{}, syntaxErr(to+j-2, unicodeNotFollowHex, nil)
+					}
+					to++
+				}
+			}
+		}
+		to++
+	}
+	return Pos{}, syntaxErr(to-1, reachedEndScanningCharacters, nil)
+}
+
+// scanStringBytewise is the byte-at-a-time version of scanString. It
+// must always agree with scanString.
+func scanStringBytewise(data []byte, i int) (Pos, error) {
 	from := i
 	to := i + 1
 	for ; to < len(data); to++ {
@@ -383,10 +638,7 @@ func scanString(data []byte, i int) (Pos, error) {
 					return Pos{}, syntaxErr(to, reachedEndScanningCharacters, nil)
 				}
 				for j, b := range data[to+1 : to+5] {
-					if b < '0' ||
-						(b > '9' && b < 'A') ||
-						(b > 'F' && b < 'a') ||
-						(b > 'f') {
+					if !isHexDigit(b) {
 						return Pos{}, syntaxErr(to+j-2, unicodeNotFollowHex, nil)
 					}
 					to++
@@ -398,6 +650,12 @@ func scanString(data []byte, i int) (Pos, error) {
 	return Pos{}, syntaxErr(to-1, reachedEndScanningCharacters, nil)
 }
 
+func isHexDigit(b byte) bool {
+	return (b >= '0' && b <= '9') ||
+		(b >= 'A' && b <= 'F') ||
+		(b >= 'a' && b <= 'f')
+}
+
 const (
 	reachedEndScanningNumber = "reached end of data scanning a number"
 	cantFindIntegerPart      = "could not find an integer part"
@@ -512,9 +770,13 @@ func scanNumber(data []byte, i int) (_ float64, _ int64, isInt bool, _ int, _ er
 		// scale up or down the value
 		if isNegExp {
 			f64 /= math.Pow(10.0, expf64)
-			if isInt && f64 == float64(int64(f64)) {
+			// powers of 10 too large for an int64 divide any int64 to 0
+			p, ok := intPow(10, exp)
+			if isInt && (ok && i64%p == 0 || !ok && i64 == 0) {
 				// still an integer
-				i64 /= intPow(10, exp)
+				if ok {
+					i64 /= p
+				}
 				isInt = true
 			} else {
 				isInt = false
@@ -522,11 +784,12 @@ func scanNumber(data []byte, i int) (_ float64, _ int64, isInt bool, _ int, _ er
 			}
 		} else {
 			f64 *= math.Pow(10.0, expf64)
-			if isInt && f64 >= float64(math.MaxInt64) {
+			p, ok := intPow(10, exp)
+			if isInt && (!ok && i64 != 0 || f64 >= float64(math.MaxInt64)) {
 				isInt = false
 				i64 = 0 // would overflow
-			} else {
-				i64 *= intPow(10, exp)
+			} else if ok {
+				i64 *= p
 			}
 		}
 	}
@@ -537,20 +800,17 @@ func scanNumber(data []byte, i int) (_ float64, _ int64, isInt bool, _ int, _ er
 	return f64, i64, isInt, i, nil
 }
 
-func intPow(n, m int64) int64 {
-	if m == 0 {
-		return 1
-	}
-
-	if m == 1 {
-		return n
-	}
-
-	result := n
-	for i := int64(2); i <= m; i++ {
+// intPow is n, which is positive, to the power of m, and false if it
+// doesn't fit in an int64.
+func intPow(n, m int64) (int64, bool) {
+	result := int64(1)
+	for i := int64(0); i < m; i++ {
+		if result > math.MaxInt64/n {
+			return 0, false
+		}
 		result *= n
 	}
-	return result
+	return result, true
 }
 
 const (
@@ -670,14 +930,34 @@ func skipWhitespace(data []byte, i int) int {
 	if i < 0 {
 		panic(fmt.Sprintf("negative i=%v", i))
 	}
+	if !useSWAR {
+		return skipWhitespaceBytewise(data, i)
+	}
+	// most values are separated by no or a single whitespace, don't
+	// bother loading whole words for those
+	if i < len(data) && data[i] > ' ' {
+		return i
+	}
+	if i+1 < len(data) && data[i+1] > ' ' && isWhitespace(data[i]) {
+		return i + 1
+	}
+	return indexNonWhitespace(data, i)
+}
+
+// skipWhitespaceBytewise is the byte-at-a-time version of
+// skipWhitespace. It must always agree with skipWhitespace.
+func skipWhitespaceBytewise(data []byte, i int) int {
 	for ; i < len(data); i++ {
-		b := data[i]
-		if b != ' ' &&
-			b != '\t' &&
-			b != '\n' &&
-			b != '\r' {
+		if !isWhitespace(data[i]) {
 			return i
 		}
 	}
 	return i
 }
+
+func isWhitespace(b byte) bool {
+	return b == ' ' ||
+		b == '\t' ||
+		b == '\n' ||
+		b == '\r'
+}
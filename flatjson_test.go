@@ -599,6 +599,23 @@ func TestScanObjects(t *testing.T) {
 	}
 }
 
+func TestScanArrayNothing(t *testing.T) {
+	for _, data := range []string{"", " \n "} {
+		_, found, err := ScanArray([]byte(data), 0, nil)
+		if found {
+			t.Errorf("%q: want nothing found", data)
+		}
+		serr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Fatalf("%q: want a syntax error, got %v", data, err)
+		}
+		if serr.Message != noOpeningSquareBracketFound || serr.Offset != len(data) {
+			t.Errorf("want %q at %d", noOpeningSquareBracketFound, len(data))
+			t.Errorf(" got %q at %d", serr.Message, serr.Offset)
+		}
+	}
+}
+
 type tfloat struct {
 	pfx   string
 	name  string
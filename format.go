@@ -0,0 +1,350 @@
+package flatjson
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"slices"
+	"sync"
+)
+
+// ReformatOptions control how documents are rewritten by Reformat.
+type ReformatOptions struct {
+	// Prefix begins every line after the first one of a document.
+	Prefix string
+	// Indent is repeated for each level of nesting. Without it, the
+	// documents are compacted.
+	Indent string
+	// SortKeys orders the members of objects by their keys.
+	SortKeys bool
+	// MaxWidth, when indenting, keeps arrays of numbers, strings,
+	// booleans and nulls on a single line if that line is no longer
+	// than MaxWidth.
+	MaxWidth int
+	// Color highlights the documents with ANSI escape codes, for
+	// terminals.
+	Color bool
+}
+
+// Compact appends to dst the document in src without insignificant
+// whitespace. Numbers and strings are copied as they are in src.
+func Compact(dst *bytes.Buffer, src []byte) error {
+	f := formatters.Get().(*formatter)
+	defer formatters.Put(f)
+	f.opts = ReformatOptions{}
+	return f.format(dst, src)
+}
+
+// Indent appends to dst an indented form of the document in src. Each
+// element of an object or array begins on a new line starting with
+// prefix, followed by one or more copies of indent according to the
+// nesting. Numbers and strings are copied as they are in src.
+func Indent(dst *bytes.Buffer, src []byte, prefix, indent string) error {
+	f := formatters.Get().(*formatter)
+	defer formatters.Put(f)
+	f.opts = ReformatOptions{Prefix: prefix, Indent: indent}
+	return f.format(dst, src)
+}
+
+// Reformat rewrites the stream of documents read from r, such as
+// NDJSON, to w according to opts. Each document is followed by a
+// newline.
+func Reformat(w io.Writer, r io.Reader, opts *ReformatOptions) error {
+	return ReformatContext(context.Background(), w, r, opts)
+}
+
+// ReformatContext is Reformat, stopped with a *ContextError when ctx is
+// done. The context is checked between documents and lines.
+func ReformatContext(ctx context.Context, w io.Writer, r io.Reader, opts *ReformatOptions) error {
+	var f formatter
+	if opts != nil {
+		f.opts = *opts
+	}
+	dr := newDocReader(ctx, r)
+	var buf bytes.Buffer
+	for {
+		doc, _, err := dr.next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		buf.Reset()
+		if err := f.format(&buf, doc); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+}
+
+const (
+	colorKey    = "\x1b[34;1m"
+	colorString = "\x1b[32m"
+	colorNumber = "\x1b[36m"
+	colorBool   = "\x1b[33m"
+	colorNull   = "\x1b[90m"
+	colorReset  = "\x1b[0m"
+)
+
+type formatter struct {
+	opts    ReformatOptions
+	data    []byte
+	out     []byte
+	members []formatMember
+}
+
+var formatters = sync.Pool{New: func() any { return new(formatter) }}
+
+// format writes the document in src to dst, in a single pass that also
+// validates it. Nothing is written if the document isn't valid.
+func (f *formatter) format(dst *bytes.Buffer, src []byte) error {
+	f.data, f.out = src, f.out[:0]
+	defer func() { f.data = nil }()
+
+	i := skipWhitespace(src, 0)
+	if i >= len(src) {
+		return syntaxErr(i, endOfDataNoValue, nil)
+	}
+	i, err := f.appendValue(i, 0, 0)
+	if err != nil {
+		return err
+	}
+	if i = skipWhitespace(src, i); i < len(src) {
+		return syntaxErr(i, unexpectedDataAfterValue, nil)
+	}
+	dst.Write(f.out)
+	return nil
+}
+
+func (f *formatter) newline(depth int) {
+	f.out = append(f.out, '\n')
+	f.out = append(f.out, f.opts.Prefix...)
+	for i := 0; i < depth; i++ {
+		f.out = append(f.out, f.opts.Indent...)
+	}
+}
+
+func (f *formatter) colored(color string, raw []byte) {
+	if f.opts.Color {
+		f.out = append(f.out, color...)
+		f.out = append(f.out, raw...)
+		f.out = append(f.out, colorReset...)
+		return
+	}
+	f.out = append(f.out, raw...)
+}
+
+// appendValue appends the value starting at i, at the given nesting
+// depth. col is the column the value starts at.
+func (f *formatter) appendValue(i, depth, col int) (int, error) {
+	data := f.data
+	switch GuessNextEntityType(data, i) {
+	case EntityType_String:
+		pos, err := scanString(data, i)
+		if err != nil {
+			return i, syntaxErr(i, beginStringValueButError, err.(*SyntaxError))
+		}
+		f.colored(colorString, pos.Bytes(data))
+		return pos.To, nil
+	case EntityType_Number:
+		_, _, _, j, err := scanNumber(data, i)
+		if err != nil {
+			return i, syntaxErr(i, beginNumberValueButError, err.(*SyntaxError))
+		}
+		f.colored(colorNumber, data[i:j])
+		return j, nil
+	case EntityType_Boolean_True:
+		f.colored(colorBool, data[i:i+4])
+		return i + 4, nil
+	case EntityType_Boolean_False:
+		f.colored(colorBool, data[i:i+5])
+		return i + 5, nil
+	case EntityType_Null:
+		f.colored(colorNull, data[i:i+4])
+		return i + 4, nil
+	case EntityType_Object:
+		j, err := f.appendObject(i, depth)
+		if err != nil {
+			return i, syntaxErr(i, beginObjectValueButError, err.(*SyntaxError))
+		}
+		return j, nil
+	case EntityType_Array:
+		j, err := f.appendArray(i, depth, col)
+		if err != nil {
+			return i, syntaxErr(i, beginArrayValueButError, err.(*SyntaxError))
+		}
+		return j, nil
+	}
+	return i, syntaxErr(i, expectValueButNoKnownType, nil)
+}
+
+// appendMember appends a name/value pair of an object.
+func (f *formatter) appendMember(first bool, name Prefix, i, depth int) (int, error) {
+	if !first {
+		f.out = append(f.out, ',')
+	}
+	if f.opts.Indent != "" {
+		f.newline(depth + 1)
+	}
+	f.colored(colorKey, name.Bytes(f.data))
+	f.out = append(f.out, ':')
+	if f.opts.Indent != "" {
+		f.out = append(f.out, ' ')
+	}
+	col := len(f.opts.Prefix) + (depth+1)*len(f.opts.Indent) + name.to - name.from + len(": ")
+	return f.appendValue(i, depth+1, col)
+}
+
+func (f *formatter) appendObject(start, depth int) (int, error) {
+	data := f.data
+	i := skipWhitespace(data, start+1)
+	if i < len(data) && data[i] == '}' {
+		f.out = append(f.out, '{', '}')
+		return i + 1, nil
+	}
+	if f.opts.SortKeys {
+		return f.appendSortedObject(start, depth)
+	}
+	f.out = append(f.out, '{')
+	for n := 0; ; n++ {
+		if i >= len(data) {
+			return i, syntaxErr(i, endOfDataNoNamePair, nil)
+		}
+		pfx, j, err := scanPairName(data, i)
+		if err != nil {
+			return i, err
+		}
+		if i, err = f.appendMember(n == 0, pfx, j, depth); err != nil {
+			return i, err
+		}
+		i = skipWhitespace(data, i)
+		if i >= len(data) {
+			return i, syntaxErr(i, endOfDataNoClosingBracket, nil)
+		}
+		switch data[i] {
+		case '}':
+			f.closeContainer('}', depth)
+			return i + 1, nil
+		case ',':
+			i = skipWhitespace(data, i+1)
+		default:
+			return i, syntaxErr(i, expectCommaOrClosingBracket, nil)
+		}
+	}
+}
+
+type formatMember struct {
+	key []byte
+	m   member
+}
+
+func (f *formatter) appendSortedObject(start, depth int) (int, error) {
+	data := f.data
+	// the members of all the objects being written are stacked up in
+	// f.members, which grows as nested objects are written
+	base := len(f.members)
+	defer func() { f.members = f.members[:base] }()
+	var err error
+	pos, scanErr := scanMembers(data, start, func(m member) bool {
+		var key []byte
+		key, err = Unquote(m.name.Bytes(data))
+		f.members = append(f.members, formatMember{key: key, m: m})
+		return err == nil
+	})
+	if err != nil {
+		return start, err
+	} else if scanErr != nil {
+		return start, scanErr
+	}
+	end := len(f.members)
+	slices.SortStableFunc(f.members[base:end], func(a, b formatMember) int {
+		return bytes.Compare(a.key, b.key)
+	})
+	f.out = append(f.out, '{')
+	for k := base; k < end; k++ {
+		m := f.members[k].m
+		if _, err := f.appendMember(k == base, m.name, m.value.From, depth); err != nil {
+			return start, err
+		}
+	}
+	f.closeContainer('}', depth)
+	return pos.To, nil
+}
+
+func (f *formatter) appendArray(start, depth, col int) (int, error) {
+	data := f.data
+	i := skipWhitespace(data, start+1)
+	if i < len(data) && data[i] == ']' {
+		f.out = append(f.out, '[', ']')
+		return i + 1, nil
+	}
+	oneLine := f.opts.Indent == "" || f.fitsOnOneLine(start, col)
+	f.out = append(f.out, '[')
+	for n := 0; ; n++ {
+		if i >= len(data) {
+			return i, syntaxErr(i, endOfDataNoValue, nil)
+		}
+		if n > 0 {
+			f.out = append(f.out, ',')
+			if oneLine && f.opts.Indent != "" {
+				f.out = append(f.out, ' ')
+			}
+		}
+		if !oneLine {
+			f.newline(depth + 1)
+			col = len(f.opts.Prefix) + (depth+1)*len(f.opts.Indent)
+		}
+		var err error
+		if i, err = f.appendValue(i, depth+1, col); err != nil {
+			return i, err
+		}
+		i = skipWhitespace(data, i)
+		if i >= len(data) {
+			return i, syntaxErr(i, endOfDataNoClosingSquareBracket, nil)
+		}
+		switch data[i] {
+		case ']':
+			if oneLine {
+				f.out = append(f.out, ']')
+			} else {
+				f.closeContainer(']', depth)
+			}
+			return i + 1, nil
+		case ',':
+			i = skipWhitespace(data, i+1)
+		default:
+			return i, syntaxErr(i, expectCommaOrClosingSquareBracket, nil)
+		}
+	}
+}
+
+func (f *formatter) closeContainer(b byte, depth int) {
+	if f.opts.Indent != "" {
+		f.newline(depth)
+	}
+	f.out = append(f.out, b)
+}
+
+// fitsOnOneLine tells if the array starting at i only holds scalars,
+// and if it can be written on a single line starting at col without
+// going past MaxWidth.
+func (f *formatter) fitsOnOneLine(i, col int) bool {
+	if f.opts.MaxWidth <= 0 {
+		return false
+	}
+	width := col + len("[]") - len(", ")
+	fits := true
+	_, err := scanMembers(f.data, i, func(m member) bool {
+		if b := f.data[m.value.From]; b == '{' || b == '[' {
+			fits = false
+			return false
+		}
+		width += m.value.To - m.value.From + len(", ")
+		fits = width <= f.opts.MaxWidth
+		return fits
+	})
+	return fits && err == nil
+}
@@ -0,0 +1,144 @@
+package flatjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var formatTests = []string{
+	`{}`,
+	`[]`,
+	`"hello"`,
+	`1.50e+10`,
+	` { "a" : [ 1 , 2.0 , "é\n" ] , "b" : { "c" : { } , "d" : [ ] , "e" : null , "f" : [ true , false ] } } `,
+	`[[[]], {"a": [{"b": "c"}]}]`,
+}
+
+func TestCompact(t *testing.T) {
+	for _, src := range formatTests {
+		var want, got bytes.Buffer
+		if err := json.Compact(&want, []byte(strings.TrimSpace(src))); err != nil {
+			t.Fatal(err)
+		}
+		if err := Compact(&got, []byte(src)); err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		if want.String() != got.String() {
+			t.Errorf("want %s", want.String())
+			t.Errorf(" got %s", got.String())
+		}
+	}
+}
+
+func TestIndent(t *testing.T) {
+	for _, src := range formatTests {
+		var want, got bytes.Buffer
+		if err := json.Indent(&want, []byte(strings.TrimSpace(src)), ">", "\t"); err != nil {
+			t.Fatal(err)
+		}
+		if err := Indent(&got, []byte(src), ">", "\t"); err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		if want.String() != got.String() {
+			t.Errorf("want %s", want.String())
+			t.Errorf(" got %s", got.String())
+		}
+	}
+}
+
+func TestCompactErrors(t *testing.T) {
+	for _, src := range []string{``, `{`, `{"a" 1}`, `[1,]`, `{} {}`} {
+		var buf bytes.Buffer
+		if err := Compact(&buf, []byte(src)); err == nil {
+			t.Errorf("%s: want an error", src)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("%s: want nothing written, got %s", src, buf.String())
+		}
+	}
+}
+
+func TestReformat(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Input string
+		Opts  *ReformatOptions
+		Want  string
+	}{
+		{
+			Name:  "ndjson compact",
+			Input: "{ \"a\" : 1 }\n\n[ 1, 2 ]\n\"x\"\n12",
+			Want:  "{\"a\":1}\n[1,2]\n\"x\"\n12\n",
+		},
+		{
+			Name:  "multi-line documents",
+			Input: "{\n  \"a\": [\n    1\n  ]\n} {\"b\":\n2}\n",
+			Want:  "{\"a\":[1]}\n{\"b\":2}\n",
+		},
+		{
+			Name:  "sorted keys",
+			Input: `{"b":1,"a":{"d":2,"c":3}}`,
+			Opts:  &ReformatOptions{SortKeys: true},
+			Want:  "{\"a\":{\"c\":3,\"d\":2},\"b\":1}\n",
+		},
+		{
+			Name:  "short arrays on one line",
+			Input: `{"short":[1,2,3],"long":[1111111111,2222222222,3333333333],"nested":[[1]]}`,
+			Opts:  &ReformatOptions{Indent: "  ", MaxWidth: 30},
+			Want: `{
+  "short": [1, 2, 3],
+  "long": [
+    1111111111,
+    2222222222,
+    3333333333
+  ],
+  "nested": [
+    [1]
+  ]
+}
+`,
+		},
+		{
+			Name:  "color",
+			Input: `{"a":["b",1,true,null]}`,
+			Opts:  &ReformatOptions{Color: true},
+			Want: "{" + colorKey + `"a"` + colorReset + ":[" +
+				colorString + `"b"` + colorReset + "," +
+				colorNumber + "1" + colorReset + "," +
+				colorBool + "true" + colorReset + "," +
+				colorNull + "null" + colorReset + "]}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := Reformat(&out, strings.NewReader(tt.Input), tt.Opts); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != tt.Want {
+				t.Errorf("want %q", tt.Want)
+				t.Errorf(" got %q", got)
+			}
+		})
+	}
+}
+
+func TestReformatErrors(t *testing.T) {
+	for _, input := range []string{
+		"{\"a\":1}\n{\"a\" 1}\n",
+		"{\"a\":1}\n{\"a\":",
+		"[1]\n[}\n[2]\n",
+	} {
+		var out bytes.Buffer
+		if err := Reformat(&out, strings.NewReader(input), nil); err == nil {
+			t.Errorf("%q: want an error", input)
+		}
+		if want, got := "{\"a\":1}\n", out.String(); !strings.HasPrefix(input, "[") && want != got {
+			t.Errorf("%q: want %q written, got %q", input, want, got)
+		}
+	}
+}
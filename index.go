@@ -0,0 +1,109 @@
+package flatjson
+
+import "sort"
+
+// Index is a structural index of a JSON document: the offsets of every
+// `{`, `}`, `[`, `]`, `:` and `,` found outside of strings, along with
+// the matching closing bracket of every opening one.
+//
+// Building an index costs a single quick pass over the document. Once
+// built, it can be shared by many scans of that same document through
+// Callbacks.Index, which lets the scanner jump straight to the end of
+// the containers it isn't going to report on.
+type Index struct {
+	size        int
+	structurals []int
+	matches     []int
+
+	stack []int
+}
+
+const (
+	unbalancedClosingBracket = "closing bracket doesn't match an opening bracket"
+	endOfDataUnclosedBracket = "end of data reached and brackets are not all closed"
+)
+
+// NewIndex builds the structural index of data.
+func NewIndex(data []byte) (*Index, error) {
+	idx := new(Index)
+	if err := idx.Reset(data); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Reset rebuilds the index for data, reusing the memory of the previous
+// index.
+func (idx *Index) Reset(data []byte) error {
+	idx.size = len(data)
+	idx.structurals = idx.structurals[:0]
+	idx.matches = idx.matches[:0]
+	idx.stack = idx.stack[:0]
+
+	for i := 0; i < len(data); i++ {
+		switch b := data[i]; b {
+		case '"':
+			pos, err := scanString(data, i)
+			if err != nil {
+				idx.size = -1
+				return syntaxErr(i, beginStringValueButError, err.(*SyntaxError))
+			}
+			i = pos.To - 1
+		case '{', '[':
+			idx.stack = append(idx.stack, len(idx.structurals))
+			idx.structurals = append(idx.structurals, i)
+			idx.matches = append(idx.matches, -1)
+		case '}', ']':
+			n := len(idx.stack)
+			if n == 0 || !bracketsMatch(data[idx.structurals[idx.stack[n-1]]], b) {
+				idx.size = -1
+				return syntaxErr(i, unbalancedClosingBracket, nil)
+			}
+			idx.matches[idx.stack[n-1]] = len(idx.structurals)
+			idx.stack = idx.stack[:n-1]
+			idx.structurals = append(idx.structurals, i)
+			idx.matches = append(idx.matches, -1)
+		case ':', ',':
+			idx.structurals = append(idx.structurals, i)
+			idx.matches = append(idx.matches, -1)
+		}
+	}
+	if len(idx.stack) != 0 {
+		idx.size = -1
+		return syntaxErr(len(data), endOfDataUnclosedBracket, nil)
+	}
+	return nil
+}
+
+// Len is the number of structural characters in the index.
+func (idx *Index) Len() int { return len(idx.structurals) }
+
+// Offset of the k-th structural character of the document.
+func (idx *Index) Offset(k int) int { return idx.structurals[k] }
+
+// Match finds the container opening at offset i and returns the offset
+// of its closing bracket. It returns false if no container opens at i.
+func (idx *Index) Match(i int) (int, bool) {
+	k := sort.SearchInts(idx.structurals, i)
+	if k == len(idx.structurals) || idx.structurals[k] != i || idx.matches[k] < 0 {
+		return 0, false
+	}
+	return idx.structurals[idx.matches[k]], true
+}
+
+// skip returns the offset one past the end of the container opening at
+// i in data, if the index describes data.
+func (idx *Index) skip(data []byte, i int) (int, bool) {
+	if idx == nil || idx.size != len(data) {
+		return 0, false
+	}
+	to, ok := idx.Match(i)
+	if !ok {
+		return 0, false
+	}
+	return to + 1, true
+}
+
+func bracketsMatch(open, close byte) bool {
+	return (open == '{' && close == '}') || (open == '[' && close == ']')
+}
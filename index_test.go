@@ -0,0 +1,143 @@
+package flatjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	data := []byte(`{"a":[1,{"b":"}]{["}],"c:,":{}}`)
+	idx, err := NewIndex(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for k := 0; k < idx.Len(); k++ {
+		got = append(got, string(data[idx.Offset(k)]))
+	}
+	want := []string{"{", ":", "[", ",", "{", ":", "}", "]", ",", ":", "{", "}", "}"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want structurals %v", want)
+		t.Errorf(" got structurals %v", got)
+	}
+
+	for _, tt := range []struct {
+		open, close int
+	}{
+		{0, 30},
+		{5, 20},
+		{8, 19},
+		{28, 29},
+	} {
+		to, ok := idx.Match(tt.open)
+		if !ok || to != tt.close {
+			t.Errorf("match of %d: want %d, got %d (%v)", tt.open, tt.close, to, ok)
+		}
+	}
+	if _, ok := idx.Match(14); ok {
+		t.Errorf("brackets inside of strings should not be indexed")
+	}
+}
+
+func TestIndexErrors(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Data          string
+		WantErrError  string
+		WantErrOffset int
+	}{
+		{
+			Name:          "mismatched brackets",
+			Data:          `{"a":[}`,
+			WantErrError:  unbalancedClosingBracket,
+			WantErrOffset: 6,
+		},
+		{
+			Name:          "too many closing brackets",
+			Data:          `{}}`,
+			WantErrError:  unbalancedClosingBracket,
+			WantErrOffset: 2,
+		},
+		{
+			Name:          "unclosed brackets",
+			Data:          `{"a":[[]`,
+			WantErrError:  endOfDataUnclosedBracket,
+			WantErrOffset: 8,
+		},
+		{
+			Name:          "unterminated string",
+			Data:          `{"a":"]}`,
+			WantErrError:  beginStringValueButError + ", " + reachedEndScanningCharacters,
+			WantErrOffset: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			_, err := NewIndex([]byte(tt.Data))
+			gotErr, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("want a syntax error, got %v", err)
+			}
+			if want, got := tt.WantErrOffset, gotErr.Offset; want != got {
+				t.Errorf("want err offset %d, was %d", want, got)
+			}
+			if want, got := tt.WantErrError, gotErr.Error(); want != got {
+				t.Errorf("want error: %q", want)
+				t.Errorf(" got error: %q", got)
+			}
+		})
+	}
+}
+
+func TestScanObjectWithIndex(t *testing.T) {
+	data := []byte(`{
+		"key":{"key2": {"deep": 2.0}},
+		"key2":["myname", 42, true, {"is":"antoine"}, [[], {}]],
+		"key3":"}]"
+	}`)
+	idx, err := NewIndex(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for maxDepth := 0; maxDepth < 4; maxDepth++ {
+		scan := func(idx *Index) []traw {
+			var raws []traw
+			_, _, err := ScanObject(data, 0, &Callbacks{
+				MaxDepth: maxDepth,
+				Index:    idx,
+				OnRaw: func(pfx Prefixes, key Prefix, value Pos) {
+					raws = append(raws, traw{
+						pfx:  pfx.AsString(data),
+						name: key.String(data),
+						raw:  value.String(data),
+					})
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return raws
+		}
+		if want, got := scan(nil), scan(idx); !reflect.DeepEqual(want, got) {
+			t.Errorf("max depth %d: want raw %+v", maxDepth, want)
+			t.Errorf("max depth %d:  got raw %+v", maxDepth, got)
+		}
+	}
+}
+
+func TestIndexResetDoesntAllocate(t *testing.T) {
+	data := []byte(`{"a":[1,2,{"b":[3,4]}],"c":{"d":"e"}}`)
+	idx, err := NewIndex(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := idx.Reset(data); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("want no allocations, got %v", allocs)
+	}
+}
@@ -0,0 +1,130 @@
+package flatjson
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"math"
+)
+
+// All iterates over every value nested in the document in data, with
+// the prefixes leading to it, its own name last. Objects and arrays
+// come after what they hold, once their end is found, as with OnValue.
+// The prefixes are only valid until the next iteration.
+//
+// A syntax error ends the iteration with a value of kind
+// EntityType_Invalid, positioned at the error.
+func All(data []byte) iter.Seq2[Prefixes, Value] {
+	return func(yield func(Prefixes, Value) bool) {
+		var (
+			st   scanState
+			path Prefixes
+		)
+		cb := &Callbacks{
+			MaxDepth: math.MaxInt,
+			OnValue: func(prefixes Prefixes, val Value) {
+				if st.stopped {
+					return
+				}
+				path = append(append(path[:0], prefixes...), val.Name)
+				st.stopped = !yield(path, val)
+			},
+		}
+		var err error
+		i := skipWhitespace(data, 0)
+		switch {
+		case i < len(data) && data[i] == '{':
+			_, _, err = scanObject(data, i, nil, cb, &st)
+		case i < len(data) && data[i] == '[':
+			_, _, err = scanArray(data, i, nil, cb, &st)
+		default:
+			_, _, err = skipValue(data, i)
+		}
+		if err != nil && !st.stopped {
+			yield(nil, invalidValue(err))
+		}
+	}
+}
+
+// Members iterates over the members of the object at objPos, without
+// looking into their values.
+//
+// A syntax error ends the iteration with a value of kind
+// EntityType_Invalid, positioned at the error.
+func Members(data []byte, objPos Pos) iter.Seq2[Prefix, Value] {
+	return func(yield func(Prefix, Value) bool) {
+		if objPos.From >= len(data) || data[objPos.From] != '{' {
+			yield(Prefix{}, invalidValue(syntaxErr(objPos.From, noOpeningBracketFound, nil)))
+			return
+		}
+		stopped := false
+		_, err := scanMembers(data, objPos.From, func(m member) bool {
+			stopped = !yield(m.name, newValue(data, m.name, m.value))
+			return !stopped
+		})
+		if err != nil && !stopped {
+			yield(Prefix{}, invalidValue(err))
+		}
+	}
+}
+
+// Elements iterates over the elements of the array at arrPos, with
+// their index, without looking into them.
+//
+// A syntax error ends the iteration with a value of kind
+// EntityType_Invalid, positioned at the error, and an index of -1.
+func Elements(data []byte, arrPos Pos) iter.Seq2[int, Value] {
+	return func(yield func(int, Value) bool) {
+		if arrPos.From >= len(data) || data[arrPos.From] != '[' {
+			yield(-1, invalidValue(syntaxErr(arrPos.From, noOpeningSquareBracketFound, nil)))
+			return
+		}
+		stopped := false
+		_, err := scanMembers(data, arrPos.From, func(m member) bool {
+			stopped = !yield(m.name.Index(), newValue(data, m.name, m.value))
+			return !stopped
+		})
+		if err != nil && !stopped {
+			yield(-1, invalidValue(err))
+		}
+	}
+}
+
+// invalidValue is where the syntax error err was found.
+func invalidValue(err error) Value {
+	var serr *SyntaxError
+	if !errors.As(err, &serr) {
+		return Value{Kind: EntityType_Invalid}
+	}
+	for serr.SubErr != nil {
+		serr = serr.SubErr
+	}
+	return Value{Kind: EntityType_Invalid, Pos: Pos{From: serr.Offset, To: serr.Offset}}
+}
+
+// Doc is a document of a stream.
+type Doc struct {
+	// Data of the document, only valid until the next iteration.
+	Data []byte
+	// Offset of the document in the stream.
+	Offset int64
+}
+
+// Documents iterates over the JSON documents of a stream, such as
+// NDJSON: documents separated by whitespace. The iteration ends with the
+// stream, or with its first error.
+func Documents(r io.Reader) iter.Seq2[Doc, error] {
+	return func(yield func(Doc, error) bool) {
+		dr := newDocReader(context.Background(), r)
+		for {
+			data, offset, err := dr.next()
+			if err == io.EOF {
+				return
+			}
+			if !yield(Doc{Data: data, Offset: offset}, err) || err != nil {
+				return
+			}
+		}
+	}
+}
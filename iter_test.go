@@ -0,0 +1,124 @@
+package flatjson
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	data := []byte(`{"a":[1,{"b":null}],"c":"d"}`)
+	var got []string
+	for path, v := range All(data) {
+		got = append(got, path.AsString(data)+"="+v.Pos.String(data))
+	}
+	want := []string{`a.0=1`, `a.1.b=null`, `a.1={"b":null}`, `a=[1,{"b":null}]`, `c="d"`}
+	if strings.Join(want, " ") != strings.Join(got, " ") {
+		t.Errorf("want %q", want)
+		t.Errorf(" got %q", got)
+	}
+
+	got = got[:0]
+	for path, v := range All([]byte(` [true,[false]]`)) {
+		got = append(got, fmt.Sprintf("%d:%d", len(path), v.Kind))
+	}
+	if want, got := "1:5 2:6 1:3", strings.Join(got, " "); want != got {
+		t.Errorf("want %s", want)
+		t.Errorf(" got %s", got)
+	}
+}
+
+func TestAllBreak(t *testing.T) {
+	data := []byte(`{"a":[1,2,3],"b":{"c":4},"d":5}`)
+	var n int
+	for range All(data) {
+		if n++; n == 2 {
+			break
+		}
+	}
+	if n != 2 {
+		t.Errorf("want 2 values, got %d", n)
+	}
+}
+
+func TestAllInvalid(t *testing.T) {
+	data := []byte(`{"a":1,"b":[2 3]}`)
+	var got []Value
+	for _, v := range All(data) {
+		got = append(got, Value{Kind: v.Kind, Pos: v.Pos})
+	}
+	want := []Value{
+		{Kind: EntityType_Number, Pos: Pos{5, 6}},
+		{Kind: EntityType_Invalid, Pos: Pos{12, 12}},
+	}
+	if fmt.Sprint(want) != fmt.Sprint(got) {
+		t.Errorf("want %v", want)
+		t.Errorf(" got %v", got)
+	}
+}
+
+func TestMembersAndElements(t *testing.T) {
+	data := []byte(`{"a":[1,"x",{"y":2}],"b":true,"c":null}`)
+	var got []string
+	for name, v := range Members(data, Pos{0, len(data)}) {
+		got = append(got, name.String(data)+"="+v.Pos.String(data))
+		if v.Kind == EntityType_Array {
+			for i, elem := range Elements(data, v.Pos) {
+				got = append(got, fmt.Sprintf("%d=%s", i, elem.Bytes(data)))
+			}
+		}
+		if v.Kind == EntityType_Boolean_True {
+			break
+		}
+	}
+	want := []string{`"a"=[1,"x",{"y":2}]`, `0=1`, `1="x"`, `2={"y":2}`, `"b"=true`}
+	if strings.Join(want, " ") != strings.Join(got, " ") {
+		t.Errorf("want %q", want)
+		t.Errorf(" got %q", got)
+	}
+
+	for i, v := range Elements(data, Pos{0, len(data)}) {
+		if i != -1 || v.Kind != EntityType_Invalid || v.Pos.From != 0 {
+			t.Errorf("want an invalid value at 0, got %d %v", i, v)
+		}
+	}
+	var last Value
+	for _, v := range Members([]byte(`{"a":1 "b":2}`), Pos{0, 13}) {
+		last = v
+	}
+	if want := (Value{Kind: EntityType_Invalid, Pos: Pos{7, 7}}); want != last {
+		t.Errorf("want %v", want)
+		t.Errorf(" got %v", last)
+	}
+}
+
+func TestDocuments(t *testing.T) {
+	stream := "{\"a\":1}\n[2,\n3]\n\"x\"\n{\"b\":"
+	var got []string
+	var lastErr error
+	for doc, err := range Documents(strings.NewReader(stream)) {
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		got = append(got, fmt.Sprintf("%d:%s", doc.Offset, doc.Data))
+	}
+	want := []string{`0:{"a":1}`, "8:[2,\n3]", `15:"x"`}
+	if strings.Join(want, " ") != strings.Join(got, " ") {
+		t.Errorf("want %q", want)
+		t.Errorf(" got %q", got)
+	}
+	if _, ok := lastErr.(*SyntaxError); !ok {
+		t.Errorf("want a syntax error for the last document, got %v", lastErr)
+	}
+
+	var n int
+	for range Documents(strings.NewReader(stream)) {
+		if n++; n == 1 {
+			break
+		}
+	}
+	if n != 1 {
+		t.Errorf("want 1 document, got %d", n)
+	}
+}
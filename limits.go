@@ -0,0 +1,190 @@
+package flatjson
+
+// ErrorCode tells what kind of problem a SyntaxError is about.
+type ErrorCode uint8
+
+const (
+	// ErrCodeSyntax is for malformed JSON.
+	ErrCodeSyntax ErrorCode = iota
+	ErrCodeDocumentTooLarge
+	ErrCodeTooDeep
+	ErrCodeStringTooLong
+	ErrCodeTooManyKeys
+	ErrCodeTooManyElements
+	ErrCodeTooManyValues
+	ErrCodeNumberTooLong
+)
+
+// Limits bound what a scan accepts, for untrusted input. A zero field
+// is no limit. Unlike MaxDepth, which only filters what's reported,
+// going over a limit fails the scan with a SyntaxError of the limit's
+// code. With limits, objects and arrays deeper than MaxDepth are
+// scanned instead of skipped, to enforce them.
+type Limits struct {
+	// MaxDocumentBytes is the size of the document.
+	MaxDocumentBytes int
+	// MaxDepth is how deeply objects and arrays nest. The document
+	// itself is at depth 1.
+	MaxDepth int
+	// MaxStringBytes is the size of strings and keys, quotes excluded
+	// and escapes included. Longer ones aren't read past the limit.
+	MaxStringBytes int
+	// MaxObjectKeys is the number of members of each object.
+	MaxObjectKeys int
+	// MaxArrayElements is the number of elements of each array.
+	MaxArrayElements int
+	// MaxValues is the number of values in the document, objects and
+	// arrays included.
+	MaxValues int
+	// MaxNumberBytes is the size of numbers, sign, fraction and exponent
+	// included. Longer ones aren't read past the limit.
+	MaxNumberBytes int
+}
+
+const (
+	documentTooLarge = "document is larger than the limit"
+	nestedTooDeep    = "objects and arrays nest deeper than the limit"
+	stringTooLong    = "string is longer than the limit"
+	tooManyKeys      = "object has more keys than the limit"
+	tooManyElements  = "array has more elements than the limit"
+	tooManyValues    = "document has more values than the limit"
+	numberTooLong    = "number is longer than the limit"
+)
+
+func limitErr(offset int, code ErrorCode, msg string) *SyntaxError {
+	err := syntaxErr(offset, msg, nil)
+	err.Code = code
+	return err
+}
+
+// scanState is what a scan keeps track of to enforce its Limits and
+// its context. A nil scanState enforces nothing.
+type scanState struct {
+	Limits
+	// start of the document
+	start  int
+	values int
+
+	// done is the context's, checked every contextCheckInterval values
+	done       <-chan struct{}
+	untilCheck int
+	// stopped ends the scan at the next value, for iterators
+	stopped bool
+
+	// reuse tells that the memory below is kept from scan to scan, by a
+	// Scanner
+	reuse bool
+	// stack is where prefixes are pushed, as deep as scans went
+	stack []Prefix
+	// dups finds the duplicate keys of objects at each depth
+	dups []*dupKeys
+}
+
+// init prepares st for a scan of the document at start, if the scan has
+// limits or a context that can be done.
+func (st *scanState) init(cb *Callbacks, start int, done <-chan struct{}) *scanState {
+	hasLimits := cb != nil && cb.Limits != (Limits{})
+	if !hasLimits && done == nil {
+		return nil
+	}
+	*st = scanState{start: start, done: done, untilCheck: contextCheckInterval}
+	if hasLimits {
+		st.Limits = cb.Limits
+	}
+	return st
+}
+
+// value accounts for a value that begins at i.
+func (st *scanState) value(i int) *SyntaxError {
+	if st == nil {
+		return nil
+	}
+	if st.stopped {
+		return limitErr(i, errCodeDone, scanStopped)
+	}
+	st.values++
+	if st.MaxValues > 0 && st.values > st.MaxValues {
+		return limitErr(i, ErrCodeTooManyValues, tooManyValues)
+	}
+	if err := st.checkDone(i); err != nil {
+		return err
+	}
+	return st.offset(i)
+}
+
+// offset checks that the document goes at least to i.
+func (st *scanState) offset(i int) *SyntaxError {
+	if st != nil && st.MaxDocumentBytes > 0 && i-st.start > st.MaxDocumentBytes {
+		return limitErr(st.start+st.MaxDocumentBytes, ErrCodeDocumentTooLarge, documentTooLarge)
+	}
+	return nil
+}
+
+// enter accounts for an object or array at i, nested in depth others.
+func (st *scanState) enter(i, depth int) *SyntaxError {
+	if st != nil && st.MaxDepth > 0 && depth+1 > st.MaxDepth {
+		return limitErr(i, ErrCodeTooDeep, nestedTooDeep)
+	}
+	return nil
+}
+
+// member accounts for the n-th member of an object or array, which
+// begins at i.
+func (st *scanState) member(i, n int, isObject bool) *SyntaxError {
+	switch {
+	case st == nil:
+	case isObject && st.MaxObjectKeys > 0 && n > st.MaxObjectKeys:
+		return limitErr(i, ErrCodeTooManyKeys, tooManyKeys)
+	case !isObject && st.MaxArrayElements > 0 && n > st.MaxArrayElements:
+		return limitErr(i, ErrCodeTooManyElements, tooManyElements)
+	}
+	return nil
+}
+
+// str checks the key or string at i before it's scanned, looking no
+// further than MaxStringBytes into it. Other errors are left for the
+// scan to find.
+func (st *scanState) str(data []byte, i int) *SyntaxError {
+	if st == nil || st.MaxStringBytes <= 0 || i+st.MaxStringBytes+2 >= len(data) || data[i] != '"' {
+		return nil
+	}
+	_, err := scanString(data[:i+st.MaxStringBytes+2], i)
+	if err != nil && err.(*SyntaxError).Message == reachedEndScanningCharacters {
+		return limitErr(i, ErrCodeStringTooLong, stringTooLong)
+	}
+	return nil
+}
+
+// number checks the number at i before it's scanned, looking no further
+// than MaxNumberBytes into it. Other errors are left for the scan to
+// find.
+func (st *scanState) number(data []byte, i int) *SyntaxError {
+	if st == nil || st.MaxNumberBytes <= 0 || i+st.MaxNumberBytes >= len(data) {
+		return nil
+	}
+	// one more byte than the limit, which the number reaches if it's
+	// too long
+	end := i + st.MaxNumberBytes + 1
+	if _, _, _, j, _ := scanNumber(data[:end], i); j >= end {
+		return limitErr(i, ErrCodeNumberTooLong, numberTooLong)
+	}
+	return nil
+}
+
+// push appends pfx to prefixes, keeping the stack for the next scans
+// when it grows.
+func (st *scanState) push(prefixes []Prefix, pfx Prefix) []Prefix {
+	prefixes = append(prefixes, pfx)
+	if st != nil && st.reuse && cap(prefixes) > cap(st.stack) {
+		st.stack = prefixes[:0]
+	}
+	return prefixes
+}
+
+// dupKeys is the reused dupKeys of objects at the given depth.
+func (st *scanState) dupKeys(depth int) *dupKeys {
+	for len(st.dups) <= depth {
+		st.dups = append(st.dups, new(dupKeys))
+	}
+	return st.dups[depth]
+}
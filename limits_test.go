@@ -0,0 +1,98 @@
+package flatjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Data          string
+		Limits        Limits
+		DuplicateKeys DuplicateKeyPolicy
+		WantCode      ErrorCode
+		WantErrOffset int
+	}{
+		{Name: "document bytes", Data: `{"a":[1,2,3,4]}`, Limits: Limits{MaxDocumentBytes: 8}, WantCode: ErrCodeDocumentTooLarge, WantErrOffset: 8},
+		{Name: "document bytes at the end", Data: `{"a":"1234"   }`, Limits: Limits{MaxDocumentBytes: 12}, WantCode: ErrCodeDocumentTooLarge, WantErrOffset: 12},
+		{Name: "document bytes fits", Data: `{"a":"1234"}` + "\n{}", Limits: Limits{MaxDocumentBytes: 12}, WantErrOffset: -1},
+		{Name: "depth", Data: `{"a":{"b":[{}]}}`, Limits: Limits{MaxDepth: 3}, WantCode: ErrCodeTooDeep, WantErrOffset: 11},
+		{Name: "depth fits", Data: `{"a":{"b":[1]}}`, Limits: Limits{MaxDepth: 3}, WantErrOffset: -1},
+		{Name: "string", Data: `{"a":"12345"}`, Limits: Limits{MaxStringBytes: 4}, WantCode: ErrCodeStringTooLong, WantErrOffset: 5},
+		{Name: "key", Data: `{"abcde":1}`, Limits: Limits{MaxStringBytes: 4}, WantCode: ErrCodeStringTooLong, WantErrOffset: 1},
+		{Name: "string not read past the limit", Data: `{"a":"1234567890`, Limits: Limits{MaxStringBytes: 4}, WantCode: ErrCodeStringTooLong, WantErrOffset: 5},
+		{Name: "key not read past the limit", Data: `{"abcdefghij`, Limits: Limits{MaxStringBytes: 4}, WantCode: ErrCodeStringTooLong, WantErrOffset: 1},
+		{Name: "string fits", Data: `{"a":"1234","b":"\u00e9"}`, Limits: Limits{MaxStringBytes: 6}, WantErrOffset: -1},
+		{Name: "string in array", Data: `{"a":["1234","12345"]}`, Limits: Limits{MaxStringBytes: 4}, WantCode: ErrCodeStringTooLong, WantErrOffset: 13},
+		{Name: "keys", Data: `{"a":1,"b":2,"c":3}`, Limits: Limits{MaxObjectKeys: 2}, WantCode: ErrCodeTooManyKeys, WantErrOffset: 13},
+		{Name: "elements", Data: `{"a":[1,2,3]}`, Limits: Limits{MaxArrayElements: 2}, WantCode: ErrCodeTooManyElements, WantErrOffset: 10},
+		{Name: "values", Data: `{"a":[1,2],"b":{}}`, Limits: Limits{MaxValues: 4}, WantCode: ErrCodeTooManyValues, WantErrOffset: 15},
+		{Name: "values fit", Data: `{"a":[1,2],"b":{}}`, Limits: Limits{MaxValues: 5}, WantErrOffset: -1},
+		{Name: "number", Data: `{"a":[1,-1.5e10]}`, Limits: Limits{MaxNumberBytes: 4}, WantCode: ErrCodeNumberTooLong, WantErrOffset: 8},
+		{Name: "number not read past the limit", Data: `{"a":[12345678.]}`, Limits: Limits{MaxNumberBytes: 4}, WantCode: ErrCodeNumberTooLong, WantErrOffset: 6},
+		{Name: "number fits", Data: `{"a":-1.5,"b":1e10}`, Limits: Limits{MaxNumberBytes: 4}, WantErrOffset: -1},
+		{Name: "number in object", Data: `{"a":123456}`, Limits: Limits{MaxNumberBytes: 4}, WantCode: ErrCodeNumberTooLong, WantErrOffset: 5},
+		{
+			Name:          "deeper than MaxDepth",
+			Data:          `{"a":{"b":[` + strings.Repeat("1,", 100) + `1]}}`,
+			Limits:        Limits{MaxArrayElements: 100},
+			WantCode:      ErrCodeTooManyElements,
+			WantErrOffset: 211,
+		},
+		{Name: "depth in a first-wins duplicate", Data: `{"a":1,"a":{"b":[{}]}}`, Limits: Limits{MaxDepth: 3}, DuplicateKeys: DuplicateKeysFirstWins, WantCode: ErrCodeTooDeep, WantErrOffset: 17},
+		{Name: "string in a first-wins duplicate", Data: `{"a":1,"a":"12345"}`, Limits: Limits{MaxStringBytes: 4}, DuplicateKeys: DuplicateKeysFirstWins, WantCode: ErrCodeStringTooLong, WantErrOffset: 11},
+		{Name: "elements in a first-wins duplicate", Data: `{"a":1,"a":[1,2,3]}`, Limits: Limits{MaxArrayElements: 2}, DuplicateKeys: DuplicateKeysFirstWins, WantCode: ErrCodeTooManyElements, WantErrOffset: 16},
+		{Name: "number in a first-wins duplicate", Data: `{"a":1,"a":123456}`, Limits: Limits{MaxNumberBytes: 4}, DuplicateKeys: DuplicateKeysFirstWins, WantCode: ErrCodeNumberTooLong, WantErrOffset: 11},
+		{Name: "depth in a last-wins duplicate", Data: `{"a":{"b":[{}]},"a":1}`, Limits: Limits{MaxDepth: 3}, DuplicateKeys: DuplicateKeysLastWins, WantCode: ErrCodeTooDeep, WantErrOffset: 11},
+		{Name: "string in a last-wins duplicate", Data: `{"a":"12345","a":1}`, Limits: Limits{MaxStringBytes: 4}, DuplicateKeys: DuplicateKeysLastWins, WantCode: ErrCodeStringTooLong, WantErrOffset: 5},
+		{Name: "elements in a last-wins duplicate", Data: `{"a":[1,2,3],"a":1}`, Limits: Limits{MaxArrayElements: 2}, DuplicateKeys: DuplicateKeysLastWins, WantCode: ErrCodeTooManyElements, WantErrOffset: 10},
+		{Name: "keys before last-wins", Data: `{"a":1,"b":2,"a":3` + strings.Repeat(`,"c":4`, 100), Limits: Limits{MaxObjectKeys: 3}, DuplicateKeys: DuplicateKeysLastWins, WantCode: ErrCodeTooManyKeys, WantErrOffset: 19},
+		{Name: "document bytes before last-wins", Data: `{"a":1,"a":[` + strings.Repeat(`1,`, 100), Limits: Limits{MaxDocumentBytes: 16}, DuplicateKeys: DuplicateKeysLastWins, WantCode: ErrCodeDocumentTooLarge, WantErrOffset: 16},
+		{Name: "last-wins fits", Data: `{"a":[1,2,3],"a":[1,2]}`, Limits: Limits{MaxArrayElements: 3, MaxDocumentBytes: 23}, DuplicateKeys: DuplicateKeysLastWins, WantErrOffset: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			_, _, err := ScanObject([]byte(tt.Data), 0, &Callbacks{MaxDepth: 1, Limits: tt.Limits, DuplicateKeys: tt.DuplicateKeys})
+			if tt.WantErrOffset < 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			serr, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("want a syntax error, got %v", err)
+			}
+			if serr.Code != tt.WantCode {
+				t.Errorf("want code %d", tt.WantCode)
+				t.Errorf(" got code %d", serr.Code)
+			}
+			for serr.SubErr != nil {
+				serr = serr.SubErr
+			}
+			if serr.Offset != tt.WantErrOffset {
+				t.Errorf("want offset %d", tt.WantErrOffset)
+				t.Errorf(" got offset %d (%v)", serr.Offset, err)
+			}
+		})
+	}
+}
+
+func TestLimitsArray(t *testing.T) {
+	data := []byte(`[1,2,[3,4,5]]`)
+	_, _, err := ScanArray(data, 0, &Callbacks{Limits: Limits{MaxArrayElements: 2}})
+	serr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("want a syntax error, got %v", err)
+	}
+	if serr.Code != ErrCodeTooManyElements {
+		t.Errorf("want code %d", ErrCodeTooManyElements)
+		t.Errorf(" got code %d", serr.Code)
+	}
+
+	_, _, err = ScanArray(data, 0, &Callbacks{Limits: Limits{MaxArrayElements: 3}})
+	if err != nil {
+		t.Errorf("want no error, got %v", err)
+	}
+}
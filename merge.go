@@ -0,0 +1,292 @@
+package flatjson
+
+import "bytes"
+
+// MergePatch applies a JSON Merge Patch (RFC 7386) to target: members of
+// the patch set to null are removed from the target, objects are merged
+// recursively, and any other value replaces what's in the target.
+//
+// When target or patch have duplicate keys, the last one wins.
+//
+// The result is a new slice, in which the bytes of target that weren't
+// changed by the patch are copied as-is.
+func MergePatch(target, patch []byte) ([]byte, error) {
+	patchPos, err := locate(patch, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateRaw(patchPos.Bytes(patch)); err != nil {
+		return nil, err
+	}
+	if patch[patchPos.From] != '{' {
+		return append([]byte(nil), patchPos.Bytes(patch)...), nil
+	}
+	targetPos, err := locate(target, nil)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := mergePatch(targetPos.Bytes(target), patchPos.Bytes(patch))
+	if err != nil {
+		return nil, err
+	}
+	return splice(target, targetPos, merged), nil
+}
+
+// mergePatch merges the patch object into the target value, going once
+// through the members of each. New members are added at the end, with
+// the separators Insert would use.
+func mergePatch(target, patch []byte) ([]byte, error) {
+	if target[0] != '{' {
+		target = []byte("{}")
+	}
+
+	// the members of the patch, where the last one of each key wins
+	var (
+		patchMembers []member
+		patchKeys    []string
+		last         = make(map[string]int)
+		key          []byte
+		innerErr     error
+	)
+	_, err := scanMembers(patch, 0, func(m member) bool {
+		if key, innerErr = Unquote(m.name.Bytes(patch)); innerErr != nil {
+			return false
+		}
+		last[string(key)] = len(patchMembers)
+		patchMembers = append(patchMembers, m)
+		patchKeys = append(patchKeys, string(key))
+		return true
+	})
+	if innerErr != nil {
+		return nil, innerErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the members of the target, and which of them each patch member
+	// applies to; the members it shadows are removed
+	var (
+		members  []member
+		shadowed []int
+	)
+	applies := make([]int, len(patchMembers))
+	for p := range applies {
+		applies[p] = -1
+	}
+	_, err = scanMembers(target, 0, func(m member) bool {
+		if key, innerErr = Unquote(m.name.Bytes(target)); innerErr != nil {
+			return false
+		}
+		if p, ok := last[string(key)]; ok {
+			if applies[p] >= 0 {
+				shadowed = append(shadowed, applies[p])
+			}
+			applies[p] = len(members)
+		}
+		members = append(members, m)
+		return true
+	})
+	if innerErr != nil {
+		return nil, innerErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	type added struct {
+		key   string
+		value []byte
+	}
+	var (
+		values   = make([][]byte, len(members))
+		removed  = make([]bool, len(members))
+		appended []added
+	)
+	for _, k := range shadowed {
+		removed[k] = true
+	}
+	for p, m := range patchMembers {
+		if last[patchKeys[p]] != p {
+			continue
+		}
+		k := applies[p]
+		value := m.value.Bytes(patch)
+		switch value[0] {
+		case 'n':
+			if k >= 0 {
+				removed[k] = true
+			}
+			continue
+		case '{':
+			current := []byte("{}")
+			if k >= 0 {
+				current = members[k].value.Bytes(target)
+			}
+			if value, err = mergePatch(current, value); err != nil {
+				return nil, err
+			}
+		}
+		if k >= 0 {
+			values[k] = value
+		} else {
+			appended = append(appended, added{key: patchKeys[p], value: value})
+		}
+	}
+	if len(members) == 0 && len(appended) == 0 {
+		return target, nil
+	}
+
+	// the members that are kept keep the separators that follow them,
+	// and those that are added copy the separators of the object
+	var kept []int
+	for k := range members {
+		if !removed[k] {
+			kept = append(kept, k)
+		}
+	}
+	out := make([]byte, 0, len(target)+len(patch))
+	out = append(out, '{')
+	sep, colon := []byte(","), []byte(":")
+	if len(kept) > 0 {
+		out = append(out, target[1:members[0].from()]...)
+		for n, k := range kept {
+			if n > 0 {
+				prev := kept[n-1]
+				out = append(out, target[members[prev].value.To:members[prev+1].from()]...)
+			}
+			out = append(out, target[members[k].from():members[k].value.From]...)
+			if values[k] != nil {
+				out = append(out, values[k]...)
+			} else {
+				out = append(out, members[k].value.Bytes(target)...)
+			}
+		}
+		if len(kept) > 1 {
+			sep = target[members[kept[0]].value.To:members[kept[0]+1].from()]
+		} else {
+			sep = append(sep, target[1:members[0].from()]...)
+		}
+		lastKept := members[kept[len(kept)-1]]
+		colon = target[lastKept.name.to:lastKept.value.From]
+	}
+	for n, a := range appended {
+		if n > 0 || len(kept) > 0 {
+			out = append(out, sep...)
+		}
+		out = appendQuote(out, a.key)
+		out = append(out, colon...)
+		out = append(out, a.value...)
+	}
+	if len(kept) > 0 {
+		out = append(out, target[members[len(members)-1].value.To:len(target)-1]...)
+	}
+	return append(out, '}'), nil
+}
+
+// CreateMergePatch computes the JSON Merge Patch (RFC 7386) that turns
+// original into modified, with as few members as possible. Members of
+// modified set to null can't be expressed by a merge patch, and end up
+// being removed when the patch is applied.
+func CreateMergePatch(original, modified []byte) ([]byte, error) {
+	origPos, err := locate(original, nil)
+	if err != nil {
+		return nil, err
+	}
+	modPos, err := locate(modified, nil)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = createMergePatch(&buf, original, origPos, modified, modPos)
+	return buf.Bytes(), err
+}
+
+func createMergePatch(buf *bytes.Buffer, original []byte, origPos Pos, modified []byte, modPos Pos) error {
+	if modified[modPos.From] != '{' {
+		buf.Write(modPos.Bytes(modified))
+		return nil
+	}
+	if original[origPos.From] != '{' {
+		original, origPos = []byte("{}"), Pos{0, 2}
+	}
+	origMembers, err := objectMembers(original, origPos.From)
+	if err != nil {
+		return err
+	}
+	modMembers, err := objectMembers(modified, modPos.From)
+	if err != nil {
+		return err
+	}
+
+	buf.WriteByte('{')
+	n := 0
+	writeKey := func(key []byte) {
+		if n > 0 {
+			buf.WriteByte(',')
+		}
+		n++
+		buf.Write(key)
+		buf.WriteByte(':')
+	}
+
+	// removed members, in the order of the original
+	var (
+		key      []byte
+		innerErr error
+	)
+	_, err = scanMembers(original, origPos.From, func(m member) bool {
+		if key, innerErr = Unquote(m.name.Bytes(original)); innerErr != nil {
+			return false
+		}
+		if _, ok := modMembers[string(key)]; !ok && origMembers[string(key)] == m.value {
+			writeKey(m.name.Bytes(original))
+			buf.WriteString("null")
+		}
+		return true
+	})
+	if innerErr != nil {
+		return innerErr
+	}
+	if err != nil {
+		return err
+	}
+
+	// added and changed members, in the order of the modified
+	_, err = scanMembers(modified, modPos.From, func(m member) bool {
+		if key, innerErr = Unquote(m.name.Bytes(modified)); innerErr != nil {
+			return false
+		}
+		origValue, ok := origMembers[string(key)]
+		if ok && modMembers[string(key)] != m.value {
+			// a duplicate key that was overridden later on
+			return true
+		}
+		switch {
+		case !ok:
+			writeKey(m.name.Bytes(modified))
+			innerErr = createMergePatch(buf, []byte("{}"), Pos{0, 2}, modified, m.value)
+		case original[origValue.From] == '{' && modified[m.value.From] == '{':
+			var sub bytes.Buffer
+			if innerErr = createMergePatch(&sub, original, origValue, modified, m.value); innerErr == nil && sub.Len() > 2 {
+				writeKey(m.name.Bytes(modified))
+				buf.Write(sub.Bytes())
+			}
+		default:
+			var eq bool
+			if eq, innerErr = equalValues(original, origValue, modified, m.value); innerErr == nil && !eq {
+				writeKey(m.name.Bytes(modified))
+				buf.Write(m.value.Bytes(modified))
+			}
+		}
+		return innerErr == nil
+	})
+	if innerErr != nil {
+		return innerErr
+	}
+	if err != nil {
+		return err
+	}
+	buf.WriteByte('}')
+	return nil
+}
@@ -0,0 +1,112 @@
+package flatjson
+
+import (
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	// from the examples of RFC 7386, appendix A
+	tests := []struct {
+		Target string
+		Patch  string
+		Want   string
+	}{
+		{Target: `{"a":"b"}`, Patch: `{"a":"c"}`, Want: `{"a":"c"}`},
+		{Target: `{"a":"b"}`, Patch: `{"b":"c"}`, Want: `{"a":"b","b":"c"}`},
+		{Target: `{"a":"b"}`, Patch: `{"a":null}`, Want: `{}`},
+		{Target: `{"a":"b","b":"c"}`, Patch: `{"a":null}`, Want: `{"b":"c"}`},
+		{Target: `{"a":["b"]}`, Patch: `{"a":"c"}`, Want: `{"a":"c"}`},
+		{Target: `{"a":"c"}`, Patch: `{"a":["b"]}`, Want: `{"a":["b"]}`},
+		{Target: `{"a":{"b":"c"}}`, Patch: `{"a":{"b":"d","c":null}}`, Want: `{"a":{"b":"d"}}`},
+		{Target: `{"a":[{"b":"c"}]}`, Patch: `{"a":[1]}`, Want: `{"a":[1]}`},
+		{Target: `["a","b"]`, Patch: `["c","d"]`, Want: `["c","d"]`},
+		{Target: `{"a":"b"}`, Patch: `["c"]`, Want: `["c"]`},
+		{Target: `{"a":"foo"}`, Patch: `null`, Want: `null`},
+		{Target: `{"a":"foo"}`, Patch: `"bar"`, Want: `"bar"`},
+		{Target: `{"e":null}`, Patch: `{"a":1}`, Want: `{"e":null,"a":1}`},
+		{Target: `[1,2]`, Patch: `{"a":"b","c":null}`, Want: `{"a":"b"}`},
+		{Target: `{}`, Patch: `{"a":{"bb":{"ccc":null}}}`, Want: `{"a":{"bb":{}}}`},
+
+		// the last of duplicate keys wins
+		{Target: `{"a":1,"b":2,"a":3}`, Patch: `{"a":4}`, Want: `{"b":2,"a":4}`},
+		{Target: `{"a":1,"a":2}`, Patch: `{"a":null}`, Want: `{}`},
+		{Target: `{"a":{"x":1},"b":2,"a":{"y":2}}`, Patch: `{"a":{"z":3}}`, Want: `{"b":2,"a":{"y":2,"z":3}}`},
+		{Target: `{"a":1}`, Patch: `{"b":{"x":1},"b":{"y":2}}`, Want: `{"a":1,"b":{"y":2}}`},
+
+		// formatting of the target is preserved
+		{
+			Target: "{\n  \"version\": 1,\n  \"flags\": {\"dark\": false}\n}\n",
+			Patch:  `{"flags": {"dark": true, "beta": true}}`,
+			Want:   "{\n  \"version\": 1,\n  \"flags\": {\"dark\": true,\"beta\": true}\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		got, err := MergePatch([]byte(tt.Target), []byte(tt.Patch))
+		if err != nil {
+			t.Errorf("%s + %s: %v", tt.Target, tt.Patch, err)
+			continue
+		}
+		if string(got) != tt.Want {
+			t.Errorf("%s + %s: want %s, got %s", tt.Target, tt.Patch, tt.Want, got)
+		}
+	}
+}
+
+func TestMergePatchErrors(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Target string
+		Patch  string
+	}{
+		{Name: "malformed member", Target: `{"a":{"b" 1}}`, Patch: `{"a":{"b":null}}`},
+		{Name: "malformed key", Target: `{"a":1,"\x":2}`, Patch: `{"a":null}`},
+		{Name: "malformed patch value", Target: `{"a":1}`, Patch: `{"a":[1 2]}`},
+		{Name: "malformed patch", Target: `{"a":1}`, Patch: `[1x]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if got, err := MergePatch([]byte(tt.Target), []byte(tt.Patch)); err == nil {
+				t.Errorf("want an error, got %s", got)
+			}
+		})
+	}
+}
+
+func TestCreateMergePatch(t *testing.T) {
+	tests := []struct {
+		Original string
+		Modified string
+		Want     string
+	}{
+		{Original: `{"a":"b"}`, Modified: `{"a":"b"}`, Want: `{}`},
+		{Original: `{"a":"b"}`, Modified: `{"a":"c"}`, Want: `{"a":"c"}`},
+		{Original: `{"a":"b"}`, Modified: `{"a":"b","b":"c"}`, Want: `{"b":"c"}`},
+		{Original: `{"a":"b","b":"c"}`, Modified: `{"b":"c"}`, Want: `{"a":null}`},
+		{Original: `{"a":1}`, Modified: `{"a":1.0}`, Want: `{}`},
+		{Original: `{"a":{"b":"c","d":1}}`, Modified: `{"a":{"b":"d","d":1}}`, Want: `{"a":{"b":"d"}}`},
+		{Original: `{"a":{"b":"c"}}`, Modified: `{"a":{"b":"c"}, "x": {"y": 1}}`, Want: `{"x":{"y":1}}`},
+		{Original: `{"a":[1,2]}`, Modified: `{"a":[1,3]}`, Want: `{"a":[1,3]}`},
+		{Original: `{"a":1}`, Modified: `["a"]`, Want: `["a"]`},
+		{Original: `["a"]`, Modified: `{"a":1}`, Want: `{"a":1}`},
+	}
+	for _, tt := range tests {
+		got, err := CreateMergePatch([]byte(tt.Original), []byte(tt.Modified))
+		if err != nil {
+			t.Errorf("%s -> %s: %v", tt.Original, tt.Modified, err)
+			continue
+		}
+		if string(got) != tt.Want {
+			t.Errorf("%s -> %s: want %s, got %s", tt.Original, tt.Modified, tt.Want, got)
+			continue
+		}
+		// applying the patch must give back the modified document
+		applied, err := MergePatch([]byte(tt.Original), got)
+		if err != nil {
+			t.Errorf("%s + %s: %v", tt.Original, got, err)
+			continue
+		}
+		if eq, err := equalValues(applied, Pos{0, len(applied)}, []byte(tt.Modified), Pos{0, len(tt.Modified)}); err != nil || !eq {
+			t.Errorf("%s + %s: want %s, got %s", tt.Original, got, tt.Modified, applied)
+		}
+	}
+}
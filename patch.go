@@ -0,0 +1,262 @@
+package flatjson
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrTestFailed is returned when a `test` operation of a JSON Patch
+	// finds a value different from the expected one.
+	ErrTestFailed = errors.New("test failed")
+	// ErrInvalidPatch is returned when a JSON Patch document is not well
+	// formed.
+	ErrInvalidPatch = errors.New("invalid patch")
+)
+
+// PatchError is an error applying one of the operations of a JSON
+// Patch.
+type PatchError struct {
+	// Index of the operation in the patch.
+	Index int
+	Op    string
+	Err   error
+}
+
+func (e *PatchError) Error() string {
+	return "operation " + strconv.Itoa(e.Index) + " (" + strconv.Quote(e.Op) + "): " + e.Err.Error()
+}
+
+func (e *PatchError) Unwrap() error { return e.Err }
+
+type patchOp struct {
+	op    string
+	path  string
+	from  string
+	value []byte
+
+	hasPath, hasFrom bool
+}
+
+// ApplyPatch applies a JSON Patch (RFC 6902) to doc. The operations are
+// applied in order, and the patch is atomic: if any operation fails, an
+// error is returned and no result.
+//
+// The result is a new slice, in which every byte that wasn't changed by
+// the patch is copied as-is from doc.
+func ApplyPatch(doc, patch []byte) ([]byte, error) {
+	ops, err := readPatch(patch)
+	if err != nil {
+		return nil, err
+	}
+	for k, op := range ops {
+		if doc, err = applyPatchOp(doc, op); err != nil {
+			return nil, &PatchError{Index: k, Op: op.op, Err: err}
+		}
+	}
+	return doc, nil
+}
+
+func readPatch(patch []byte) ([]patchOp, error) {
+	var (
+		ops    []patchOp
+		opErr  error
+		notObj = -1
+	)
+	_, found, err := ScanArray(patch, 0, &Callbacks{
+		MaxDepth: 1,
+		OnRaw: func(prefixes Prefixes, name Prefix, value Pos) {
+			index := name.Index()
+			if len(prefixes) != 0 {
+				index = prefixes[0].Index()
+			}
+			for len(ops) <= index {
+				ops = append(ops, patchOp{})
+			}
+			if len(prefixes) == 0 {
+				if patch[value.From] != '{' && notObj < 0 {
+					notObj = index
+				}
+				return
+			}
+			op := &ops[index]
+			switch {
+			case name.EqualString(patch, "op"):
+				op.op, opErr = patchString(patch, value, opErr)
+			case name.EqualString(patch, "path"):
+				op.path, opErr = patchString(patch, value, opErr)
+				op.hasPath = true
+			case name.EqualString(patch, "from"):
+				op.from, opErr = patchString(patch, value, opErr)
+				op.hasFrom = true
+			case name.EqualString(patch, "value"):
+				op.value = value.Bytes(patch)
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	} else if !found {
+		return nil, ErrInvalidPatch
+	} else if notObj >= 0 {
+		return nil, &PatchError{Index: notObj, Err: ErrInvalidPatch}
+	} else if opErr != nil {
+		return nil, opErr
+	}
+	return ops, nil
+}
+
+func patchString(patch []byte, value Pos, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	if patch[value.From] != '"' {
+		return "", ErrInvalidPatch
+	}
+	s, err := Unquote(value.Bytes(patch))
+	return string(s), err
+}
+
+func applyPatchOp(doc []byte, op patchOp) ([]byte, error) {
+	if !op.hasPath {
+		return nil, ErrInvalidPatch
+	}
+	path, err := ParsePointer(op.path)
+	if err != nil {
+		return nil, err
+	}
+	var from []string
+	if op.op == "move" || op.op == "copy" {
+		if !op.hasFrom {
+			return nil, ErrInvalidPatch
+		}
+		if from, err = ParsePointer(op.from); err != nil {
+			return nil, err
+		}
+	}
+	switch op.op {
+	case "add", "replace", "test":
+		if op.value == nil {
+			return nil, ErrInvalidPatch
+		}
+	}
+
+	switch op.op {
+	case "add":
+		return patchAdd(doc, path, op.value)
+
+	case "remove":
+		return Delete(doc, path)
+
+	case "replace":
+		if _, err := locate(doc, path); err != nil {
+			return nil, err
+		}
+		return Set(doc, path, op.value)
+
+	case "move":
+		pos, err := locate(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if isPathPrefix(from, path) {
+			if len(from) == len(path) {
+				return doc, nil
+			}
+			// can't move a value into itself
+			return nil, pathErr(path, ErrInvalidPatch)
+		}
+		value := append([]byte(nil), pos.Bytes(doc)...)
+		if doc, err = Delete(doc, from); err != nil {
+			return nil, err
+		}
+		return patchAdd(doc, path, value)
+
+	case "copy":
+		pos, err := locate(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return patchAdd(doc, path, pos.Bytes(doc))
+
+	case "test":
+		pos, err := locate(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if eq, err := equalValues(doc, pos, op.value, Pos{0, len(op.value)}); err != nil {
+			return nil, err
+		} else if !eq {
+			return nil, pathErr(path, ErrTestFailed)
+		}
+		return doc, nil
+	}
+	return nil, ErrInvalidPatch
+}
+
+// patchAdd implements the `add` operation: it inserts into arrays, and
+// adds or replaces in objects.
+func patchAdd(doc []byte, path []string, value []byte) ([]byte, error) {
+	if len(path) == 0 {
+		return Set(doc, path, value)
+	}
+	parentPath, last := path[:len(path)-1], path[len(path)-1]
+	parent, members, err := locateMembers(doc, parentPath)
+	if err != nil {
+		return nil, err
+	}
+	if doc[parent.From] == '[' {
+		if last == "-" {
+			last = strconv.Itoa(len(members))
+		}
+		return Insert(doc, parentPath, last, value)
+	}
+	return Set(doc, path, value)
+}
+
+func isPathPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ParsePointer splits a JSON Pointer (RFC 6901) into the path it
+// designates. The empty pointer designates the whole document.
+func ParsePointer(ptr string) ([]string, error) {
+	if ptr == "" {
+		return nil, nil
+	}
+	if ptr[0] != '/' {
+		return nil, errors.New("JSON pointer " + strconv.Quote(ptr) + " doesn't begin with a `/`")
+	}
+	path := strings.Split(ptr[1:], "/")
+	for i, elem := range path {
+		if strings.IndexByte(elem, '~') == -1 {
+			continue
+		}
+		var bd strings.Builder
+		for j := 0; j < len(elem); j++ {
+			if elem[j] != '~' {
+				bd.WriteByte(elem[j])
+				continue
+			}
+			if j+1 < len(elem) && elem[j+1] == '0' {
+				bd.WriteByte('~')
+			} else if j+1 < len(elem) && elem[j+1] == '1' {
+				bd.WriteByte('/')
+			} else {
+				return nil, errors.New("JSON pointer " + strconv.Quote(ptr) + " has an invalid `~` escape")
+			}
+			j++
+		}
+		path[i] = bd.String()
+	}
+	return path, nil
+}
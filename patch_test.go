@@ -0,0 +1,245 @@
+package flatjson
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Doc   string
+		Patch string
+
+		Want    string
+		WantErr error
+	}{
+		// from the examples of RFC 6902, appendix A
+		{
+			Name:  "adding an object member",
+			Doc:   `{ "foo": "bar"}`,
+			Patch: `[{ "op": "add", "path": "/baz", "value": "qux" }]`,
+			Want:  `{ "foo": "bar", "baz": "qux"}`,
+		},
+		{
+			Name:  "adding an array element",
+			Doc:   `{ "foo": [ "bar", "baz" ] }`,
+			Patch: `[{ "op": "add", "path": "/foo/1", "value": "qux" }]`,
+			Want:  `{ "foo": [ "bar", "qux", "baz" ] }`,
+		},
+		{
+			Name:  "removing an object member",
+			Doc:   `{ "baz": "qux", "foo": "bar" }`,
+			Patch: `[{ "op": "remove", "path": "/baz" }]`,
+			Want:  `{ "foo": "bar" }`,
+		},
+		{
+			Name:  "removing an array element",
+			Doc:   `{ "foo": [ "bar", "qux", "baz" ] }`,
+			Patch: `[{ "op": "remove", "path": "/foo/1" }]`,
+			Want:  `{ "foo": [ "bar", "baz" ] }`,
+		},
+		{
+			Name:  "replacing a value",
+			Doc:   `{ "baz": "qux", "foo": "bar" }`,
+			Patch: `[{ "op": "replace", "path": "/baz", "value": "boo" }]`,
+			Want:  `{ "baz": "boo", "foo": "bar" }`,
+		},
+		{
+			Name:  "moving a value",
+			Doc:   `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			Patch: `[{ "op": "move", "from": "/foo/waldo", "path": "/qux/thud" }]`,
+			Want:  `{"foo": {"bar": "baz"}, "qux": {"corge": "grault","thud": "fred"}}`,
+		},
+		{
+			Name:  "moving an array element",
+			Doc:   `{ "foo": [ "all", "grass", "cows", "eat" ] }`,
+			Patch: `[{ "op": "move", "from": "/foo/1", "path": "/foo/3" }]`,
+			Want:  `{ "foo": [ "all", "cows", "eat", "grass" ] }`,
+		},
+		{
+			Name: "testing a value: success",
+			Doc:  `{ "baz": "qux", "foo": [ "a", 2, "c" ] }`,
+			Patch: `[
+				{ "op": "test", "path": "/baz", "value": "qux" },
+				{ "op": "test", "path": "/foo/1", "value": 2.0 }
+			]`,
+			Want: `{ "baz": "qux", "foo": [ "a", 2, "c" ] }`,
+		},
+		{
+			Name:    "testing a value: error",
+			Doc:     `{ "baz": "qux" }`,
+			Patch:   `[{ "op": "test", "path": "/baz", "value": "bar" }]`,
+			WantErr: ErrTestFailed,
+		},
+		{
+			Name:  "adding a nested member object",
+			Doc:   `{ "foo": "bar" }`,
+			Patch: `[{ "op": "add", "path": "/child", "value": { "grandchild": { } } }]`,
+			Want:  `{ "foo": "bar", "child": { "grandchild": { } } }`,
+		},
+		{
+			Name:  "ignoring unrecognized elements",
+			Doc:   `{ "foo": "bar" }`,
+			Patch: `[{ "op": "add", "path": "/baz", "value": "qux", "xyz": 123 }]`,
+			Want:  `{ "foo": "bar", "baz": "qux" }`,
+		},
+		{
+			Name:    "adding to a nonexistent target",
+			Doc:     `{ "foo": "bar" }`,
+			Patch:   `[{ "op": "add", "path": "/baz/bat", "value": "qux" }]`,
+			WantErr: ErrPathNotFound,
+		},
+		{
+			Name:  "~ escape ordering",
+			Doc:   `{ "/": 9, "~1": 10 }`,
+			Patch: `[{"op": "test", "path": "/~01", "value": 10}]`,
+			Want:  `{ "/": 9, "~1": 10 }`,
+		},
+		{
+			Name:    "comparing strings and numbers",
+			Doc:     `{ "/": 9, "~1": 10 }`,
+			Patch:   `[{"op": "test", "path": "/~01", "value": "10"}]`,
+			WantErr: ErrTestFailed,
+		},
+		{
+			Name:  "adding an array value",
+			Doc:   `{ "foo": ["bar"] }`,
+			Patch: `[{ "op": "add", "path": "/foo/-", "value": ["abc", "def"] }]`,
+			Want:  `{ "foo": ["bar",["abc", "def"]] }`,
+		},
+
+		// more
+		{
+			Name:  "copying a value",
+			Doc:   `{"a": {"b": [1, 2]}}`,
+			Patch: `[{ "op": "copy", "from": "/a/b", "path": "/c" }]`,
+			Want:  `{"a": {"b": [1, 2]},"c": [1, 2]}`,
+		},
+		{
+			Name:  "replacing the whole document",
+			Doc:   `{"a": 1}`,
+			Patch: `[{ "op": "replace", "path": "", "value": [1] }]`,
+			Want:  `[1]`,
+		},
+		{
+			Name:  "testing objects regardless of order",
+			Doc:   `{"a": {"b": 1, "c": [true, null]}}`,
+			Patch: `[{ "op": "test", "path": "/a", "value": {"c": [true, null], "b": 1} }]`,
+			Want:  `{"a": {"b": 1, "c": [true, null]}}`,
+		},
+		{
+			Name:    "replacing a missing value",
+			Doc:     `{"a": 1}`,
+			Patch:   `[{ "op": "replace", "path": "/b", "value": 2 }]`,
+			WantErr: ErrPathNotFound,
+		},
+		{
+			Name:    "moving a value into itself",
+			Doc:     `{"a": {"b": 1}}`,
+			Patch:   `[{ "op": "move", "from": "/a", "path": "/a/c" }]`,
+			WantErr: ErrInvalidPatch,
+		},
+		{
+			Name:  "moving a value to where it is",
+			Doc:   `{"a": {"b": 1}}`,
+			Patch: `[{ "op": "move", "from": "/a/b", "path": "/a/b" }]`,
+			Want:  `{"a": {"b": 1}}`,
+		},
+		{
+			Name:    "moving a missing value to where it is",
+			Doc:     `{"a": 1}`,
+			Patch:   `[{ "op": "move", "from": "/nope", "path": "/nope" }]`,
+			WantErr: ErrPathNotFound,
+		},
+		{
+			Name:    "leading zeros in index",
+			Doc:     `{"a": [1, 2]}`,
+			Patch:   `[{ "op": "remove", "path": "/a/01" }]`,
+			WantErr: ErrPathNotFound,
+		},
+		{
+			Name:    "unknown operation",
+			Doc:     `{"a": 1}`,
+			Patch:   `[{ "op": "frobnicate", "path": "/a" }]`,
+			WantErr: ErrInvalidPatch,
+		},
+		{
+			Name:    "missing value",
+			Doc:     `{"a": 1}`,
+			Patch:   `[{ "op": "add", "path": "/b" }]`,
+			WantErr: ErrInvalidPatch,
+		},
+		{
+			Name:    "operation isn't an object",
+			Doc:     `{"a": 1}`,
+			Patch:   `[{ "op": "remove", "path": "/a" }, 1]`,
+			WantErr: ErrInvalidPatch,
+		},
+		{
+			Name:    "empty operation",
+			Doc:     `{"a": 1}`,
+			Patch:   `[{ "op": "remove", "path": "/a" }, {}]`,
+			WantErr: ErrInvalidPatch,
+		},
+		{
+			Name: "atomicity",
+			Doc:  `{"a": 1}`,
+			Patch: `[
+				{ "op": "remove", "path": "/a" },
+				{ "op": "test", "path": "/a", "value": 1 }
+			]`,
+			WantErr: ErrPathNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			doc := []byte(tt.Doc)
+			got, err := ApplyPatch(doc, []byte(tt.Patch))
+			if string(doc) != tt.Doc {
+				t.Errorf("input was modified: %s", doc)
+			}
+			if tt.WantErr != nil {
+				if !errors.Is(err, tt.WantErr) {
+					t.Errorf("want error %v, got %v", tt.WantErr, err)
+				}
+				if got != nil {
+					t.Errorf("want no result, got %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.Want {
+				t.Errorf("want %s", tt.Want)
+				t.Errorf(" got %s", got)
+			}
+		})
+	}
+}
+
+func TestParsePointer(t *testing.T) {
+	tests := []struct {
+		Ptr     string
+		Want    []string
+		WantErr bool
+	}{
+		{Ptr: "", Want: nil},
+		{Ptr: "/", Want: []string{""}},
+		{Ptr: "/foo/0", Want: []string{"foo", "0"}},
+		{Ptr: "/a~1b/m~0n/~01", Want: []string{"a/b", "m~n", "~1"}},
+		{Ptr: "foo", WantErr: true},
+		{Ptr: "/a~2", WantErr: true},
+		{Ptr: "/a~", WantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePointer(tt.Ptr)
+		if tt.WantErr != (err != nil) {
+			t.Errorf("%q: want error %v, got %v", tt.Ptr, tt.WantErr, err)
+		} else if !reflect.DeepEqual(tt.Want, got) {
+			t.Errorf("%q: want %q, got %q", tt.Ptr, tt.Want, got)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package flatjson
+
+import "strings"
+
+// SplitPath splits a dotted path, like the ones returned by
+// Prefixes.AsString, into its elements.
+func SplitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// matchPath tells if path matches pattern, where a `*` element of the
+// pattern matches any single element of the path, and a `**` element
+// matches any number of them, including none.
+func matchPath(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(path); skip++ {
+				if matchPath(pattern[1:], path[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 || (pattern[0] != "*" && pattern[0] != path[0]) {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
+
+// matchPrefixes is matchPath for the prefixes leading to a value in
+// data, which it avoids turning into strings.
+func matchPrefixes(pattern []string, data []byte, path []Prefix) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(path); skip++ {
+				if matchPrefixes(pattern[1:], data, path[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 || (pattern[0] != "*" && !prefixEquals(data, path[0], pattern[0])) {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
+
+// prefixEquals tells if a prefix designates the path element elem.
+func prefixEquals(data []byte, pfx Prefix, elem string) bool {
+	if pfx.IsArrayIndex() {
+		index, ok := parseIndex(elem)
+		return ok && index == pfx.Index()
+	}
+	return pfx.EqualString(data, elem)
+}
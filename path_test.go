@@ -0,0 +1,44 @@
+package flatjson
+
+import "testing"
+
+func TestMatchPath(t *testing.T) {
+	data := []byte(`{"user":{"email":"a","tags":["x"]},"a.b":1}`)
+	user := newObjectKeyPrefix(1, 7)
+	email := newObjectKeyPrefix(9, 16)
+	tags := newObjectKeyPrefix(21, 27)
+	dotted := newObjectKeyPrefix(35, 40)
+
+	tests := []struct {
+		Pattern string
+		Path    []Prefix
+		Want    bool
+	}{
+		{Pattern: "user.email", Path: []Prefix{user, email}, Want: true},
+		{Pattern: "user.*", Path: []Prefix{user, email}, Want: true},
+		{Pattern: "*.email", Path: []Prefix{user, email}, Want: true},
+		{Pattern: "*", Path: []Prefix{user, email}, Want: false},
+		{Pattern: "user", Path: []Prefix{user, email}, Want: false},
+		{Pattern: "**", Path: []Prefix{user, email}, Want: true},
+		{Pattern: "**.email", Path: []Prefix{user, email}, Want: true},
+		{Pattern: "**.email", Path: []Prefix{email}, Want: true},
+		{Pattern: "user.**.email", Path: []Prefix{user, email}, Want: true},
+		{Pattern: "**.tags.0", Path: []Prefix{user, tags, newArrayIndexPrefix(0)}, Want: true},
+		{Pattern: "**.tags.*", Path: []Prefix{user, tags, newArrayIndexPrefix(0)}, Want: true},
+		{Pattern: "**.tags.1", Path: []Prefix{user, tags, newArrayIndexPrefix(0)}, Want: false},
+		{Pattern: "**.tags.00", Path: []Prefix{user, tags, newArrayIndexPrefix(0)}, Want: false},
+		{Pattern: "a.b", Path: []Prefix{dotted}, Want: false},
+	}
+	for _, tt := range tests {
+		var path []string
+		for _, pfx := range tt.Path {
+			path = append(path, Prefixes{pfx}.AsString(data))
+		}
+		if got := matchPrefixes(SplitPath(tt.Pattern), data, tt.Path); got != tt.Want {
+			t.Errorf("matchPrefixes(%q, %q): want %v, got %v", tt.Pattern, path, tt.Want, got)
+		}
+		if got := matchPath(SplitPath(tt.Pattern), path); got != tt.Want {
+			t.Errorf("matchPath(%q, %q): want %v, got %v", tt.Pattern, path, tt.Want, got)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package flatjson
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ProjectOptions control how Project selects values.
+type ProjectOptions struct {
+	// Renames gives, for some of the selected paths, the path their
+	// values take in the projection. The n-th `*` element of a new path
+	// stands for what the n-th `*` of the selected path matched.
+	Renames map[string]string
+	// UniqueKeys declares that no object has duplicate keys. Objects
+	// and arrays are then only scanned until all that's selected in them
+	// was found, and what follows isn't checked: the first of duplicate
+	// keys wins.
+	UniqueKeys bool
+}
+
+// Project returns a JSON document made only of the values at paths of
+// data, nested as they are in data. Paths are dotted, and a `*` element
+// matches any single key or index, while a `**` element matches any
+// number of them. Values are copied verbatim. Arrays in the projection
+// only hold the selected elements, in order. When an object has
+// duplicate keys, the last one wins: nothing is selected in the members
+// it shadows. Data must be a single valid document.
+func Project(data []byte, paths []string) ([]byte, error) {
+	return ProjectWith(data, paths, nil)
+}
+
+// ProjectWith is Project with options.
+func ProjectWith(data []byte, paths []string, opts *ProjectOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &ProjectOptions{}
+	}
+	p := projector{data: data, unique: opts.UniqueKeys, nodes: make([]projNode, 1)}
+	states := make([]projState, 0, len(paths))
+	for _, path := range paths {
+		pp, err := newProjPath(path, opts.Renames[path])
+		if err != nil {
+			return nil, err
+		}
+		p.paths = append(p.paths, pp)
+		states = p.reach(states, projState{path: len(p.paths) - 1})
+	}
+	if !opts.UniqueKeys {
+		if err := validateRaw(data); err != nil {
+			return nil, err
+		}
+	}
+	start := skipWhitespace(data, 0)
+	// paths made only of `**` match the whole document
+	deeper := states[:0]
+	for _, s := range states {
+		if s.at < len(p.paths[s.path].pattern) {
+			deeper = append(deeper, s)
+			continue
+		}
+		_, end, err := skipValue(data, start)
+		if err != nil {
+			return nil, err
+		}
+		p.insert(s.path, Pos{From: start, To: end})
+	}
+	if p.nodes[0].isLeaf {
+		return p.appendNode(nil, 0), nil
+	}
+	if start < len(data) && (data[start] == '{' || data[start] == '[') {
+		if err := p.walk(start, deeper); err != nil {
+			return nil, err
+		}
+	} else if _, _, err := skipValue(data, start); err != nil {
+		return nil, err
+	}
+	return p.appendNode(nil, 0), nil
+}
+
+type projPath struct {
+	pattern []string
+	// as is the renamed path, if any, where wildcards are the depths of
+	// what stands for its `*` elements
+	as        []string
+	wildcards []int
+}
+
+func newProjPath(path, as string) (projPath, error) {
+	pp := projPath{pattern: SplitPath(path)}
+	if path == "" {
+		return pp, fmt.Errorf("projecting an empty path")
+	}
+	if as == "" {
+		return pp, nil
+	}
+	pp.as = SplitPath(as)
+	for depth, elem := range pp.pattern {
+		if elem == "*" {
+			pp.wildcards = append(pp.wildcards, depth)
+		}
+	}
+	used := 0
+	for _, elem := range pp.as {
+		switch elem {
+		case "*":
+			used++
+		case "**":
+			return pp, fmt.Errorf("renaming %q to %q: `**` can't be renamed", path, as)
+		}
+	}
+	if used > len(pp.wildcards) {
+		return pp, fmt.Errorf("renaming %q to %q: more `*` than in the path", path, as)
+	}
+	if used > 0 && strings.Contains("."+path+".", ".**.") {
+		return pp, fmt.Errorf("renaming %q to %q: `*` can't be renamed in a path with `**`", path, as)
+	}
+	return pp, nil
+}
+
+// projState is how far a path got to match the current path.
+type projState struct {
+	path int
+	at   int
+}
+
+type projector struct {
+	data   []byte
+	paths  []projPath
+	unique bool
+	// path leading to the container being walked
+	path  []Prefix
+	nodes []projNode
+}
+
+// projNode is a value of the projection: either a value copied from
+// the data, or an object or array of other nodes.
+type projNode struct {
+	key      projKey
+	value    Pos
+	isLeaf   bool
+	children []int
+}
+
+// projKey is an object key, quoted, or an array index.
+type projKey struct {
+	raw   []byte
+	index int
+}
+
+func (k projKey) equal(o projKey) bool {
+	if k.raw == nil || o.raw == nil {
+		return k.raw == nil && o.raw == nil && k.index == o.index
+	}
+	if bytes.Equal(k.raw, o.raw) {
+		return true
+	}
+	// the same key can be escaped in different ways
+	s, err := Unquote(o.raw)
+	return err == nil && Unquoter{}.Equal(k.raw, unsafeBytesToString(s))
+}
+
+// walk goes through the members of the container at i, as far as the
+// states of the paths require.
+func (p *projector) walk(i int, states []projState) error {
+	// with unique keys, once each literal element was seen, nothing else
+	// in here can match
+	var literals []string
+	canStop := p.unique
+	for _, s := range states {
+		elem := p.paths[s.path].pattern[s.at]
+		if elem == "*" || elem == "**" {
+			canStop = false
+			break
+		}
+		if !contains(literals, elem) {
+			literals = append(literals, elem)
+		}
+	}
+	seen := 0
+	// without unique keys, nothing matches in members shadowed by a
+	// later one
+	var dups *dupKeys
+	if !p.unique && p.data[i] == '{' {
+		dups = new(dupKeys)
+		dups.reset(p.data, Unquoter{})
+		if err := dups.scanKeys(i, nil); err != nil {
+			return err
+		}
+	}
+
+	var (
+		next  []projState
+		err   error
+		index = -1
+	)
+	_, scanErr := scanMembers(p.data, i, func(m member) bool {
+		index++
+		if dups != nil && dups.shadowed(index) {
+			return true
+		}
+		next = next[:0]
+		for _, s := range states {
+			next = p.advance(next, s, m.name)
+		}
+		if len(next) == 0 {
+			return true
+		}
+		if canStop {
+			seen++
+		}
+		p.path = append(p.path, m.name)
+		defer func() { p.path = p.path[:len(p.path)-1] }()
+
+		deeper := next[:0]
+		for _, s := range next {
+			if s.at == len(p.paths[s.path].pattern) {
+				p.insert(s.path, m.value)
+			} else {
+				deeper = append(deeper, s)
+			}
+		}
+		if b := p.data[m.value.From]; len(deeper) > 0 && (b == '{' || b == '[') {
+			if err = p.walk(m.value.From, append([]projState(nil), deeper...)); err != nil {
+				return false
+			}
+		}
+		return !canStop || seen < len(literals)
+	})
+	if err != nil {
+		return err
+	}
+	return scanErr
+}
+
+// advance adds to next the states of a path after the member name.
+func (p *projector) advance(next []projState, s projState, name Prefix) []projState {
+	pattern := p.paths[s.path].pattern
+	if s.at == len(pattern) {
+		return next
+	}
+	switch elem := pattern[s.at]; {
+	case elem == "**":
+		// it goes on matching deeper; that it stops here is the state
+		// that reach added after it
+		return p.reach(next, s)
+	case elem == "*" || prefixEquals(p.data, name, elem):
+		return p.reach(next, projState{path: s.path, at: s.at + 1})
+	}
+	return next
+}
+
+// reach adds s to states, along with the states that follow it when
+// its `**` elements match nothing.
+func (p *projector) reach(states []projState, s projState) []projState {
+	pattern := p.paths[s.path].pattern
+	for {
+		states = appendState(states, s)
+		if s.at == len(pattern) || pattern[s.at] != "**" {
+			return states
+		}
+		s.at++
+	}
+}
+
+func appendState(states []projState, s projState) []projState {
+	for _, o := range states {
+		if o == s {
+			return states
+		}
+	}
+	return append(states, s)
+}
+
+func contains(elems []string, elem string) bool {
+	for _, e := range elems {
+		if e == elem {
+			return true
+		}
+	}
+	return false
+}
+
+// insert places value in the projection, where the path it matched
+// puts it.
+func (p *projector) insert(path int, value Pos) {
+	pp := p.paths[path]
+	n := 0
+	enter := func(key projKey) bool {
+		if p.nodes[n].isLeaf {
+			// the value is already there as part of another one
+			return false
+		}
+		for _, c := range p.nodes[n].children {
+			if p.nodes[c].key.equal(key) {
+				n = c
+				return true
+			}
+		}
+		p.nodes = append(p.nodes, projNode{key: key})
+		p.nodes[n].children = append(p.nodes[n].children, len(p.nodes)-1)
+		n = len(p.nodes) - 1
+		return true
+	}
+	if pp.as == nil {
+		for _, pfx := range p.path {
+			if !enter(p.keyOf(pfx)) {
+				return
+			}
+		}
+	} else {
+		used := 0
+		for _, elem := range pp.as {
+			var key projKey
+			if elem == "*" {
+				key = p.keyOf(p.path[pp.wildcards[used]])
+				used++
+			} else {
+				key = projKey{raw: appendQuote(nil, elem)}
+			}
+			if !enter(key) {
+				return
+			}
+		}
+	}
+	p.nodes[n].isLeaf = true
+	p.nodes[n].value = value
+	p.nodes[n].children = nil
+}
+
+func (p *projector) keyOf(pfx Prefix) projKey {
+	if pfx.IsArrayIndex() {
+		return projKey{index: pfx.Index()}
+	}
+	return projKey{raw: p.data[pfx.from:pfx.to]}
+}
+
+// appendNode appends the JSON of node n. Nodes with only array indices
+// are arrays, the others are objects.
+func (p *projector) appendNode(dst []byte, n int) []byte {
+	node := &p.nodes[n]
+	if node.isLeaf {
+		return append(dst, p.data[node.value.From:node.value.To]...)
+	}
+	isArray := len(node.children) > 0
+	for _, c := range node.children {
+		if p.nodes[c].key.raw != nil {
+			isArray = false
+		}
+	}
+	if isArray {
+		dst = append(dst, '[')
+	} else {
+		dst = append(dst, '{')
+	}
+	for k, c := range node.children {
+		if k > 0 {
+			dst = append(dst, ',')
+		}
+		if !isArray {
+			if key := p.nodes[c].key; key.raw != nil {
+				dst = append(dst, key.raw...)
+			} else {
+				dst = append(dst, '"')
+				dst = strconv.AppendInt(dst, int64(key.index), 10)
+				dst = append(dst, '"')
+			}
+			dst = append(dst, ':')
+		}
+		dst = p.appendNode(dst, c)
+	}
+	if isArray {
+		return append(dst, ']')
+	}
+	return append(dst, '}')
+}
@@ -0,0 +1,107 @@
+package flatjson
+
+import (
+	"testing"
+)
+
+func TestProject(t *testing.T) {
+	event := `{
+  "id": "e1",
+  "user": {"id": 7, "email": "a@b.c", "name": "Ann"},
+  "items": [{"sku": "x", "qty": 1}, {"sku": "y", "qty": 2}],
+  "meta": {"trace": {"id": "t1"}, "tags": ["a", "b"]}
+}`
+	tests := []struct {
+		Name    string
+		Data    string
+		Paths   []string
+		Renames map[string]string
+		Unique  bool
+		Want    string
+	}{
+		{Name: "keys", Data: event, Paths: []string{"id", "user.email"}, Want: `{"id":"e1","user":{"email":"a@b.c"}}`},
+		{Name: "document order", Data: event, Paths: []string{"user.email", "id"}, Want: `{"id":"e1","user":{"email":"a@b.c"}}`},
+		{Name: "container", Data: event, Paths: []string{"meta.trace"}, Want: `{"meta":{"trace":{"id": "t1"}}}`},
+		{Name: "enclosing value", Data: event, Paths: []string{"meta.trace.id", "meta"}, Want: `{"meta":{"trace": {"id": "t1"}, "tags": ["a", "b"]}}`},
+		{Name: "missing", Data: event, Paths: []string{"nope", "user.nope"}, Want: `{}`},
+		{Name: "array wildcard", Data: event, Paths: []string{"items.*.sku"}, Want: `{"items":[{"sku":"x"},{"sku":"y"}]}`},
+		{Name: "array index", Data: event, Paths: []string{"items.1.qty", "meta.tags.1"}, Want: `{"items":[{"qty":2}],"meta":{"tags":["b"]}}`},
+		{Name: "object wildcard", Data: event, Paths: []string{"*.id"}, Want: `{"user":{"id":7}}`},
+		{Name: "any depth", Data: event, Paths: []string{"**.id"}, Want: `{"id":"e1","user":{"id":7},"meta":{"trace":{"id":"t1"}}}`},
+		{Name: "any depth below", Data: `{"a":{"b":1,"c":2},"d":3}`, Paths: []string{"a.**"}, Want: `{"a":{"b":1,"c":2}}`},
+		{Name: "any depth between", Data: `{"a":{"c":1,"b":{"c":2,"d":{"c":3}}},"c":4}`, Paths: []string{"a.**.c"}, Want: `{"a":{"c":1,"b":{"c":2,"d":{"c":3}}}}`},
+		{Name: "everything", Data: `{"a":{"b":1},"d":[3]}`, Paths: []string{"**"}, Want: `{"a":{"b":1},"d":[3]}`},
+		{Name: "everything in a scalar", Data: ` 1 `, Paths: []string{"**"}, Want: `1`},
+		{
+			Name:    "rename",
+			Data:    event,
+			Paths:   []string{"user.email", "meta.trace.id"},
+			Renames: map[string]string{"user.email": "email", "meta.trace.id": "trace_id"},
+			Want:    `{"email":"a@b.c","trace_id":"t1"}`,
+		},
+		{
+			Name:    "rename with wildcards",
+			Data:    event,
+			Paths:   []string{"items.*.sku"},
+			Renames: map[string]string{"items.*.sku": "skus.*"},
+			Want:    `{"skus":["x","y"]}`,
+		},
+		{
+			Name:    "rename with object wildcards",
+			Data:    `{"a":{"x":1},"b":{"x":2}}`,
+			Paths:   []string{"*.x"},
+			Renames: map[string]string{"*.x": "x.*"},
+			Want:    `{"x":{"a":1,"b":2}}`,
+		},
+		{Name: "duplicate keys", Data: `{"a":1,"b":2,"a":3}`, Paths: []string{"a"}, Want: `{"a":3}`},
+		{Name: "escaped duplicate keys", Data: `{"a":{"b":1},"\u0061":{"c":2}}`, Paths: []string{"a.b", "a.c"}, Want: `{"\u0061":{"c":2}}`},
+		{Name: "shadowed duplicate keys", Data: `{"a":{"x":1},"a":{"y":2}}`, Paths: []string{"a.x"}, Want: `{}`},
+		{Name: "shadowed duplicate keys at any depth", Data: `{"a":{"b":{"x":1}},"a":{"b":{"y":2}}}`, Paths: []string{"**.x", "**.y"}, Want: `{"a":{"b":{"y":2}}}`},
+		{Name: "escaped duplicate values", Data: `{"a":1,"\u0061":2}`, Paths: []string{"a"}, Want: `{"\u0061":2}`},
+		{Name: "unique keys", Data: `{"a":1,"b":2,"a":3}`, Paths: []string{"a"}, Unique: true, Want: `{"a":1}`},
+		{Name: "unique keys stop early", Data: `{"a":{"b":1,"c":2},"d":[}`, Paths: []string{"a.b"}, Unique: true, Want: `{"a":{"b":1}}`},
+		{Name: "top level array", Data: `[{"a":1,"b":2},{"b":3}]`, Paths: []string{"*.b"}, Want: `[{"b":2},{"b":3}]`},
+		{Name: "scalar", Data: `1`, Paths: []string{"a"}, Want: `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			got, err := ProjectWith([]byte(tt.Data), tt.Paths, &ProjectOptions{Renames: tt.Renames, UniqueKeys: tt.Unique})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.Want {
+				t.Errorf("want %s", tt.Want)
+				t.Errorf(" got %s", got)
+			}
+			if err := validateRaw(got); err != nil {
+				t.Errorf("invalid projection: %v", err)
+			}
+		})
+	}
+}
+
+func TestProjectErrors(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Data    string
+		Paths   []string
+		Renames map[string]string
+	}{
+		{Name: "syntax", Data: `{"a":}`, Paths: []string{"a"}},
+		{Name: "syntax after", Data: `{"a":1,"b":}`, Paths: []string{"a"}},
+		{Name: "syntax in what's not selected", Data: `{"a":{"b":1x},"c":1}`, Paths: []string{"a"}},
+		{Name: "syntax in a shadowed member", Data: `{"a":[1 2],"a":1}`, Paths: []string{"a"}},
+		{Name: "data after the document", Data: `{"a":1} garbage`, Paths: []string{"a"}},
+		{Name: "empty path", Data: `{}`, Paths: []string{""}},
+		{Name: "too many wildcards", Data: `{}`, Paths: []string{"a.*"}, Renames: map[string]string{"a.*": "*.*"}},
+		{Name: "rename any depth", Data: `{}`, Paths: []string{"**.a"}, Renames: map[string]string{"**.a": "**"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			_, err := ProjectWith([]byte(tt.Data), tt.Paths, &ProjectOptions{Renames: tt.Renames})
+			if err == nil {
+				t.Errorf("want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package flatjson
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"unicode/utf8"
+)
+
+// RedactAction is what a RedactRule does to the values it matches.
+type RedactAction uint8
+
+const (
+	// RedactReplace replaces values by the rule's Value.
+	RedactReplace RedactAction = iota + 1
+	// RedactHash replaces values by the hex encoded HMAC-SHA256 of
+	// their content, keyed with the rule's Key. Equal values hash the
+	// same, so they can still be correlated.
+	RedactHash
+	// RedactTruncate keeps the first Length characters of strings.
+	// Other values are left as they are.
+	RedactTruncate
+	// RedactRemove removes the values, along with their key in objects.
+	RedactRemove
+)
+
+// RedactRule pairs a path pattern with what to do to the values found
+// at matching paths.
+type RedactRule struct {
+	// Path is a dotted path, such as `user.email`. A `*` element matches
+	// any single key or index, and a `**` element matches any number of
+	// them, such as in `**.password`.
+	Path   string
+	Action RedactAction
+	// Value is the JSON value used by RedactReplace, `"[REDACTED]"` if
+	// empty.
+	Value []byte
+	// Key is the secret key used by RedactHash.
+	Key []byte
+	// Length is the number of characters kept by RedactTruncate.
+	Length int
+}
+
+var defaultRedactValue = []byte(`"[REDACTED]"`)
+
+// Redactor applies a set of rules to documents. It can be reused, but
+// not concurrently.
+type Redactor struct {
+	rules   []redactRule
+	path    []Prefix
+	matches []redactMatch
+	scratch []byte
+	// unquoted holds the strings being hashed or truncated
+	unquoted []byte
+}
+
+type redactRule struct {
+	RedactRule
+	pattern []string
+}
+
+type redactMatch struct {
+	name  Prefix
+	value Pos
+	rule  *redactRule
+}
+
+// from is where the match begins, including its key in objects.
+func (m redactMatch) from() int {
+	if m.name.IsObjectKey() {
+		return m.name.from
+	}
+	return m.value.From
+}
+
+// NewRedactor checks the rules and prepares them to be applied.
+func NewRedactor(rules []RedactRule) (*Redactor, error) {
+	r := &Redactor{rules: make([]redactRule, 0, len(rules))}
+	for i, rule := range rules {
+		if rule.Path == "" {
+			return nil, fmt.Errorf("redact rule %d: empty path", i)
+		}
+		switch rule.Action {
+		case RedactReplace:
+			if len(rule.Value) == 0 {
+				rule.Value = defaultRedactValue
+			} else if err := validateRaw(rule.Value); err != nil {
+				return nil, fmt.Errorf("redact rule %d: invalid value: %w", i, err)
+			}
+		case RedactHash:
+			if len(rule.Key) == 0 {
+				return nil, fmt.Errorf("redact rule %d: no key to hash with", i)
+			}
+		case RedactTruncate:
+			if rule.Length < 0 {
+				return nil, fmt.Errorf("redact rule %d: negative length %d", i, rule.Length)
+			}
+		case RedactRemove:
+		default:
+			return nil, fmt.Errorf("redact rule %d: unknown action %d", i, rule.Action)
+		}
+		r.rules = append(r.rules, redactRule{RedactRule: rule, pattern: SplitPath(rule.Path)})
+	}
+	return r, nil
+}
+
+// Redact returns a copy of data where the values matching the rules
+// are redacted. When many rules match a value, the first one applies.
+// Values nested in a value that's redacted are left to it. Everything
+// else is copied verbatim.
+func Redact(data []byte, rules []RedactRule) ([]byte, error) {
+	r, err := NewRedactor(rules)
+	if err != nil {
+		return nil, err
+	}
+	return r.AppendRedact(nil, data)
+}
+
+// AppendRedact appends to dst a redacted copy of the document in data.
+func (r *Redactor) AppendRedact(dst, data []byte) ([]byte, error) {
+	return r.appendRedact(context.Background(), dst, data)
+}
+
+func (r *Redactor) appendRedact(ctx context.Context, dst, data []byte) ([]byte, error) {
+	r.matches = r.matches[:0]
+	cb := &Callbacks{MaxDepth: math.MaxInt, OnRaw: r.match(data)}
+	start := skipWhitespace(data, 0)
+	var err error
+	switch {
+	case start == len(data):
+		return dst, syntaxErr(start, expectValueButNoKnownType, nil)
+	case data[start] == '{':
+		_, _, err = ScanObjectContext(ctx, data, start, cb)
+	case data[start] == '[':
+		_, _, err = ScanArrayContext(ctx, data, start, cb)
+	default:
+		// a scalar has no path that rules could match
+		_, _, err = skipValue(data, start)
+	}
+	if err != nil {
+		return dst, err
+	}
+	// values are reported after what they contain, so bring the
+	// outermost matches first and forget those inside them
+	slices.SortFunc(r.matches, func(a, b redactMatch) int {
+		return a.value.From - b.value.From
+	})
+	last := 0
+	for _, m := range r.matches {
+		if m.value.From < last {
+			continue
+		}
+		if m.rule.Action != RedactRemove {
+			dst = append(dst, data[last:m.value.From]...)
+			dst = r.replacement(dst, data, m)
+			last = m.value.To
+			continue
+		}
+		span := removalSpan(data, m, last)
+		dst = append(dst, data[last:span.From]...)
+		last = span.To
+	}
+	return append(dst, data[last:]...), nil
+}
+
+// Stream copies the documents read from rd, such as NDJSON, to w with
+// the rules applied. Each document is followed by a newline.
+func (r *Redactor) Stream(w io.Writer, rd io.Reader) error {
+	return r.StreamContext(context.Background(), w, rd)
+}
+
+// StreamContext is Stream, stopped with a *ContextError when ctx is
+// done. The context is checked between documents and lines, and every
+// so often within documents.
+func (r *Redactor) StreamContext(ctx context.Context, w io.Writer, rd io.Reader) error {
+	dr := newDocReader(ctx, rd)
+	var buf []byte
+	for {
+		doc, off, err := dr.next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		buf, err = r.appendRedact(ctx, buf[:0], doc)
+		if err != nil {
+			var (
+				serr *SyntaxError
+				cerr *ContextError
+			)
+			if errors.As(err, &serr) {
+				serr.Offset += int(off)
+			} else if errors.As(err, &cerr) {
+				cerr.Offset += off
+			}
+			return err
+		}
+		buf = append(buf, '\n')
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+}
+
+// match returns the callback that records the values matched by the
+// rules.
+func (r *Redactor) match(data []byte) func(Prefixes, Prefix, Pos) {
+	return func(prefixes Prefixes, name Prefix, value Pos) {
+		r.path = append(append(r.path[:0], prefixes...), name)
+		for i := range r.rules {
+			rule := &r.rules[i]
+			if matchPrefixes(rule.pattern, data, r.path) {
+				r.matches = append(r.matches, redactMatch{name: name, value: value, rule: rule})
+				return
+			}
+		}
+	}
+}
+
+// replacement appends what replaces the value of m.
+func (r *Redactor) replacement(dst, data []byte, m redactMatch) []byte {
+	raw := data[m.value.From:m.value.To]
+	switch m.rule.Action {
+	case RedactReplace:
+		return append(dst, m.rule.Value...)
+	case RedactHash:
+		content := raw
+		if raw[0] == '"' {
+			var err error
+			if r.unquoted, err = (String{Value: m.value}).Unquote(data, r.unquoted); err == nil {
+				content = r.unquoted
+			}
+		}
+		mac := hmac.New(sha256.New, m.rule.Key)
+		mac.Write(content)
+		r.scratch = mac.Sum(r.scratch[:0])
+		dst = append(dst, '"')
+		dst = hex.AppendEncode(dst, r.scratch)
+		return append(dst, '"')
+	case RedactTruncate:
+		if raw[0] != '"' {
+			return append(dst, raw...)
+		}
+		var err error
+		r.unquoted, err = (String{Value: m.value}).Unquote(data, r.unquoted)
+		s := r.unquoted
+		if err != nil || utf8.RuneCount(s) <= m.rule.Length {
+			return append(dst, raw...)
+		}
+		end := 0
+		for n := 0; n < m.rule.Length; n++ {
+			_, size := utf8.DecodeRune(s[end:])
+			end += size
+		}
+		return appendQuote(dst, unsafeBytesToString(s[:end]))
+	}
+	return append(dst, raw...)
+}
+
+// removalSpan is what to cut to remove the member of m, along with one
+// separator around it. The separator before it is preferred, unless it
+// was already cut along with a previous member, which ends at last.
+func removalSpan(data []byte, m redactMatch, last int) Pos {
+	from := m.from()
+	before := from - 1
+	for before >= 0 && isWhitespace(data[before]) {
+		before--
+	}
+	if before >= last && data[before] == ',' {
+		return Pos{before, m.value.To}
+	}
+	after := skipWhitespace(data, m.value.To)
+	if after < len(data) && data[after] == ',' {
+		return Pos{from, skipWhitespace(data, after+1)}
+	}
+	return Pos{from, m.value.To}
+}
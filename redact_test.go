@@ -0,0 +1,185 @@
+package flatjson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Rules []RedactRule
+		Data  string
+		Want  string
+	}{
+		{
+			Name:  "replace",
+			Rules: []RedactRule{{Path: "user.email", Action: RedactReplace}},
+			Data:  `{"user":{"name":"bob","email":"bob@example.com"}}`,
+			Want:  `{"user":{"name":"bob","email":"[REDACTED]"}}`,
+		},
+		{
+			Name:  "replace with value",
+			Rules: []RedactRule{{Path: "headers.authorization", Action: RedactReplace, Value: []byte(`null`)}},
+			Data:  "{\"headers\": {\"authorization\": \"Bearer abc\", \"accept\": \"*/*\"}}",
+			Want:  "{\"headers\": {\"authorization\": null, \"accept\": \"*/*\"}}",
+		},
+		{
+			Name:  "wildcard",
+			Rules: []RedactRule{{Path: "*.password", Action: RedactReplace}},
+			Data:  `{"password":"a","db":{"password":"b"},"x":{"y":{"password":"c"}}}`,
+			Want:  `{"password":"a","db":{"password":"[REDACTED]"},"x":{"y":{"password":"c"}}}`,
+		},
+		{
+			Name:  "any depth",
+			Rules: []RedactRule{{Path: "**.password", Action: RedactReplace}},
+			Data:  `{"password":"a","db":[{"password":"b"}],"x":{"y":{"password":{"old":"c"}}}}`,
+			Want:  `{"password":"[REDACTED]","db":[{"password":"[REDACTED]"}],"x":{"y":{"password":"[REDACTED]"}}}`,
+		},
+		{
+			Name:  "array index",
+			Rules: []RedactRule{{Path: "cards.1", Action: RedactReplace}},
+			Data:  `{"cards":["a","b","c"]}`,
+			Want:  `{"cards":["a","[REDACTED]","c"]}`,
+		},
+		{
+			Name:  "outermost match wins",
+			Rules: []RedactRule{{Path: "**.email", Action: RedactRemove}, {Path: "user", Action: RedactReplace}},
+			Data:  `{"user":{"email":"a"},"email":"b","id":1}`,
+			Want:  `{"user":"[REDACTED]","id":1}`,
+		},
+		{
+			Name:  "first rule wins",
+			Rules: []RedactRule{{Path: "a", Action: RedactReplace, Value: []byte(`1`)}, {Path: "*", Action: RedactReplace, Value: []byte(`2`)}},
+			Data:  `{"a":0,"b":0}`,
+			Want:  `{"a":1,"b":2}`,
+		},
+		{
+			Name:  "truncate",
+			Rules: []RedactRule{{Path: "*", Action: RedactTruncate, Length: 3}},
+			Data:  `{"a":"héllo","b":"hi","c":12345,"d":"étés"}`,
+			Want:  `{"a":"hél","b":"hi","c":12345,"d":"été"}`,
+		},
+		{
+			Name:  "hash",
+			Rules: []RedactRule{{Path: "*", Action: RedactHash, Key: []byte("key")}},
+			Data:  `{"a":"The quick brown fox jumps over the lazy dog","b":"The quick brown fox jumps over the lazy dog"}`,
+			Want:  `{"a":"f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8","b":"f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"}`,
+		},
+		{
+			Name:  "remove last",
+			Rules: []RedactRule{{Path: "b", Action: RedactRemove}},
+			Data:  "{\n  \"a\": 1,\n  \"b\": 2\n}",
+			Want:  "{\n  \"a\": 1\n}",
+		},
+		{
+			Name:  "remove first",
+			Rules: []RedactRule{{Path: "a", Action: RedactRemove}},
+			Data:  "{\n  \"a\": 1,\n  \"b\": 2\n}",
+			Want:  "{\n  \"b\": 2\n}",
+		},
+		{
+			Name:  "remove only",
+			Rules: []RedactRule{{Path: "a", Action: RedactRemove}},
+			Data:  `{"a":{"b":1}}`,
+			Want:  `{}`,
+		},
+		{
+			Name:  "remove adjacent",
+			Rules: []RedactRule{{Path: "a", Action: RedactRemove}, {Path: "b", Action: RedactRemove}},
+			Data:  `{"a":1,"b":2,"c":3}`,
+			Want:  `{"c":3}`,
+		},
+		{
+			Name:  "remove adjacent at the end",
+			Rules: []RedactRule{{Path: "b", Action: RedactRemove}, {Path: "c", Action: RedactRemove}},
+			Data:  `{"a":1,"b":2,"c":3}`,
+			Want:  `{"a":1}`,
+		},
+		{
+			Name:  "remove all",
+			Rules: []RedactRule{{Path: "*", Action: RedactRemove}},
+			Data:  `{"a":1, "b":2, "c":3}`,
+			Want:  `{}`,
+		},
+		{
+			Name:  "remove array elements",
+			Rules: []RedactRule{{Path: "*.1", Action: RedactRemove}, {Path: "*.2", Action: RedactRemove}},
+			Data:  `{"a":[0,1,2,3],"b":[0,1,2]}`,
+			Want:  `{"a":[0,3],"b":[0]}`,
+		},
+		{
+			Name:  "top level array",
+			Rules: []RedactRule{{Path: "*.token", Action: RedactRemove}},
+			Data:  `[{"id":1,"token":"x"},{"token":"y","id":2}]`,
+			Want:  `[{"id":1},{"id":2}]`,
+		},
+		{
+			Name:  "scalar",
+			Rules: []RedactRule{{Path: "*", Action: RedactRemove}},
+			Data:  ` "text" `,
+			Want:  ` "text" `,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			got, err := Redact([]byte(tt.Data), tt.Rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.Want {
+				t.Errorf("want %s", tt.Want)
+				t.Errorf(" got %s", got)
+			}
+		})
+	}
+}
+
+func TestRedactErrors(t *testing.T) {
+	invalid := [][]RedactRule{
+		{{Path: "", Action: RedactReplace}},
+		{{Path: "a"}},
+		{{Path: "a", Action: RedactReplace, Value: []byte(`{`)}},
+		{{Path: "a", Action: RedactHash}},
+		{{Path: "a", Action: RedactTruncate, Length: -1}},
+	}
+	for _, rules := range invalid {
+		if _, err := NewRedactor(rules); err == nil {
+			t.Errorf("%+v: want an error", rules)
+		}
+	}
+
+	_, err := Redact([]byte(`{"a":}`), []RedactRule{{Path: "a", Action: RedactRemove}})
+	if _, ok := err.(*SyntaxError); !ok {
+		t.Errorf("want a syntax error, got %v", err)
+	}
+}
+
+func TestRedactorStream(t *testing.T) {
+	r, err := NewRedactor([]RedactRule{{Path: "user.email", Action: RedactRemove}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := "{\"user\":{\"id\":1,\"email\":\"a@b.c\"}}\n\n{\"user\":\n  {\"email\":\"d@e.f\"}}\n[1]\n"
+	want := "{\"user\":{\"id\":1}}\n{\"user\":\n  {}}\n[1]\n"
+	var out bytes.Buffer
+	if err := r.Stream(&out, strings.NewReader(in)); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != want {
+		t.Errorf("want %q", want)
+		t.Errorf(" got %q", out.String())
+	}
+
+	out.Reset()
+	err = r.Stream(&out, strings.NewReader("{\"a\":1}\n{\"a\":}\n"))
+	serr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("want a syntax error, got %v", err)
+	}
+	if want := 13; serr.Offset != want {
+		t.Errorf("want offset %d", want)
+		t.Errorf(" got offset %d", serr.Offset)
+	}
+}
@@ -2,6 +2,7 @@ package flatjson
 
 import (
 	"math"
+	"reflect"
 	"testing"
 )
 
@@ -124,6 +125,16 @@ func TestScanStrings(t *testing.T) {
 				t.Errorf("want val %+v", want)
 				t.Errorf(" got val %+v", got)
 			}
+
+			bytewiseVal, bytewiseErr := scanStringBytewise([]byte(tt.Data), tt.Start)
+			if want, got := gotVal, bytewiseVal; want != got {
+				t.Errorf("want bytewise val %+v", want)
+				t.Errorf(" got bytewise val %+v", got)
+			}
+			if !reflect.DeepEqual(err, bytewiseErr) {
+				t.Errorf("want bytewise err %v", err)
+				t.Errorf(" got bytewise err %v", bytewiseErr)
+			}
 		})
 	}
 }
@@ -1040,6 +1051,9 @@ func TestSkipWhitespace(t *testing.T) {
 			if want != got {
 				t.Errorf("want advance to %d, got %d", want, got)
 			}
+			if got := skipWhitespaceBytewise([]byte(tt.Data), tt.Start); want != got {
+				t.Errorf("want bytewise advance to %d, got %d", want, got)
+			}
 		})
 	}
 }
@@ -38,15 +38,35 @@ func scanSeparator(data []byte, from int) (int, error) {
 // ScanArray according to the spec at http://www.json.org/
 // but ignoring nested objects and arrays
 func ScanArray(data []byte, from int, cb *Callbacks) (pos Pos, found bool, err error) {
-	return scanArray(data, from, nil, cb)
+	var st scanState
+	return scanArray(data, from, nil, cb, st.init(cb, from, nil))
 }
 
-func scanArray(data []byte, from int, prefixes []Prefix, cb *Callbacks) (pos Pos, found bool, _ error) {
+func scanArray(data []byte, from int, prefixes []Prefix, cb *Callbacks, st *scanState) (pos Pos, found bool, _ error) {
 	pos.From, pos.To = -1, -1
 	start := skipWhitespace(data, from)
-	if len(data) == 0 || data[start] != '[' {
+	if start >= len(data) || data[start] != '[' {
 		return pos, false, syntaxErr(start, noOpeningSquareBracketFound, nil)
 	}
+	if err := st.enter(start, len(prefixes)); err != nil {
+		return pos, false, err
+	}
+	if len(prefixes) == 0 {
+		if err := st.value(start); err != nil {
+			return pos, false, err
+		}
+	}
+	if cb.skipsDepth(len(prefixes)) {
+		// nothing in here will be reported, only find where it ends
+		to, ok := cb.Index.skip(data, start)
+		if !ok {
+			var err error
+			if to, err = skipContainer(data, start); err != nil {
+				return pos, false, err
+			}
+		}
+		return Pos{start, to}, true, nil
+	}
 	i := start + 1
 	for index := -1; i < len(data); i++ {
 		index++
@@ -57,58 +77,79 @@ func scanArray(data []byte, from int, prefixes []Prefix, cb *Callbacks) (pos Pos
 		}
 
 		if data[i] == ']' {
+			if err := st.offset(i + 1); err != nil {
+				return pos, false, err
+			}
 			return Pos{start, i + 1}, true, nil
 		}
+		if err := st.member(i, index+1, false); err != nil {
+			return pos, false, err
+		}
+		if err := st.value(i); err != nil {
+			return pos, false, err
+		}
 
 		// decide if the value is a number, string, object, array, bool or null
 		et := GuessNextEntityType(data, i)
 
 		var (
 			valPos Pos
+			num    numberValue
 			err    error
 		)
 		if et == EntityType_String { // strings
+			if err := st.str(data, i); err != nil {
+				return pos, false, err
+			}
 			valPos, err = scanString(data, i)
 			if err != nil {
 				return pos, false, syntaxErr(i, beginStringValueButError, err.(*SyntaxError))
 			}
+			if err := cb.checkUTF8(data, valPos); err != nil {
+				return pos, false, err
+			}
 
-			if cb != nil && cb.OnString != nil && cb.MaxDepth >= len(prefixes) {
+			if cb.reports(len(prefixes)) && cb.OnString != nil {
 				cb.OnString(prefixes, String{Name: newArrayIndexPrefix(index), Value: valPos})
 			}
 			i = valPos.To
 
 		} else if et == EntityType_Object { // objects
-			valPos, found, err = scanObject(data, i, append(prefixes, newArrayIndexPrefix(index)), cb) // TODO: fix recursion
+			valPos, found, err = scanObject(data, i, st.push(prefixes, newArrayIndexPrefix(index)), cb, st) // TODO: fix recursion
 			if err != nil {
-				return Pos{}, found, syntaxErr(i, beginObjectValueButError, err.(*SyntaxError))
+				return Pos{}, found, nestedErr(i, beginObjectValueButError, err)
 			} else if !found {
 				return Pos{}, found, syntaxErr(i, expectValueButNoKnownType, nil)
 			}
 			i = valPos.To
 
 		} else if et == EntityType_Array { // arrays
-			valPos, found, err = scanArray(data, i, append(prefixes, newArrayIndexPrefix(index)), cb) // TODO: fix recursion
+			valPos, found, err = scanArray(data, i, st.push(prefixes, newArrayIndexPrefix(index)), cb, st) // TODO: fix recursion
 			if err != nil {
-				return Pos{}, found, syntaxErr(i, beginArrayValueButError, err.(*SyntaxError))
+				return Pos{}, found, nestedErr(i, beginArrayValueButError, err)
 			} else if !found {
 				return Pos{}, found, syntaxErr(i, expectValueButNoKnownType, nil)
 			}
 			i = valPos.To
 
 		} else if et == EntityType_Number { // numbers
+			if err := st.number(data, i); err != nil {
+				return pos, false, err
+			}
 			f64, i64, isInt, j, err := ScanNumber(data, i)
 			if err != nil {
 				return pos, false, syntaxErr(i, beginNumberValueButError, err.(*SyntaxError))
 			}
+			num = numberValue{f64: f64, i64: i64, isInt: isInt}
 			j = skipWhitespace(data, j)
 			if j < len(data) && data[j] != ',' && data[j] != ']' {
 				return pos, false, syntaxErr(i, malformedNumber, nil)
 			}
-			if cb != nil && cb.MaxDepth >= len(prefixes) {
-				if isInt && cb.OnInteger != nil {
+			if cb.reports(len(prefixes)) {
+				switch {
+				case isInt && cb.OnInteger != nil:
 					cb.OnInteger(prefixes, Integer{Name: newArrayIndexPrefix(index), Value: i64})
-				} else if cb.OnFloat != nil {
+				case cb.OnFloat != nil:
 					cb.OnFloat(prefixes, Float{Name: newArrayIndexPrefix(index), Value: f64})
 				}
 			}
@@ -117,7 +158,7 @@ func scanArray(data []byte, from int, prefixes []Prefix, cb *Callbacks) (pos Pos
 
 		} else if et == EntityType_Boolean_True {
 
-			if cb != nil && cb.OnBoolean != nil && cb.MaxDepth >= len(prefixes) {
+			if cb.reports(len(prefixes)) && cb.OnBoolean != nil {
 				cb.OnBoolean(prefixes, Bool{Name: newArrayIndexPrefix(index), Value: true})
 			}
 			valPos = Pos{From: i, To: i + 4}
@@ -125,7 +166,7 @@ func scanArray(data []byte, from int, prefixes []Prefix, cb *Callbacks) (pos Pos
 
 		} else if et == EntityType_Boolean_False {
 
-			if cb != nil && cb.OnBoolean != nil && cb.MaxDepth >= len(prefixes) {
+			if cb.reports(len(prefixes)) && cb.OnBoolean != nil {
 				cb.OnBoolean(prefixes, Bool{Name: newArrayIndexPrefix(index), Value: false})
 			}
 			valPos = Pos{From: i, To: i + 5}
@@ -133,7 +174,7 @@ func scanArray(data []byte, from int, prefixes []Prefix, cb *Callbacks) (pos Pos
 
 		} else if et == EntityType_Null {
 
-			if cb != nil && cb.OnNull != nil && cb.MaxDepth >= len(prefixes) {
+			if cb.reports(len(prefixes)) && cb.OnNull != nil {
 				cb.OnNull(prefixes, Null{Name: newArrayIndexPrefix(index)})
 			}
 			valPos = Pos{From: i, To: i + 4}
@@ -145,6 +186,14 @@ func scanArray(data []byte, from int, prefixes []Prefix, cb *Callbacks) (pos Pos
 		if cb != nil && cb.OnRaw != nil && cb.MaxDepth >= len(prefixes) {
 			cb.OnRaw(prefixes, newArrayIndexPrefix(index), valPos)
 		}
+		if cb != nil && cb.OnValue != nil && cb.MaxDepth >= len(prefixes) {
+			cb.OnValue(prefixes, Value{Kind: et, Name: newArrayIndexPrefix(index), Pos: valPos, num: num, u: cb.Unquoter()})
+		}
+		if cb.reports(len(prefixes)) && cb.failable() {
+			if err := cb.reportErr(prefixes, Value{Kind: et, Name: newArrayIndexPrefix(index), Pos: valPos, num: num, u: cb.Unquoter()}); err != nil {
+				return pos, false, err
+			}
+		}
 
 		i = skipWhitespace(data, i)
 		if i < len(data) {
@@ -152,9 +201,52 @@ func scanArray(data []byte, from int, prefixes []Prefix, cb *Callbacks) (pos Pos
 				// more values to come
 				// TODO(antoine): be kind and accept trailing commas
 			} else if data[i] == ']' {
+				if err := st.offset(i + 1); err != nil {
+					return pos, false, err
+				}
 				return Pos{start, i + 1}, true, nil
 			}
 		}
 	}
 	return pos, false, syntaxErr(i, endOfDataNoClosingSquareBracket, nil)
 }
+
+// Scanner scans objects and arrays as ScanObject and ScanArray do, but
+// keeps what it needs from one scan to the next: its stack of prefixes,
+// and what it finds duplicate keys with. Once it has scanned documents
+// like those it's given, scanning them allocates nothing.
+//
+// The zero value is ready to use. A Scanner can't be used concurrently,
+// but can be kept in a sync.Pool once Reset.
+type Scanner struct {
+	st scanState
+}
+
+// Reset forgets the last scan, and what data it was of, keeping the
+// memory for the next one.
+func (s *Scanner) Reset() {
+	for _, d := range s.st.dups {
+		d.reset(nil, Unquoter{})
+	}
+	s.st = scanState{reuse: true, stack: s.st.stack[:0], dups: s.st.dups}
+}
+
+// ScanObject is ScanObject, with the memory of the scanner.
+func (s *Scanner) ScanObject(data []byte, from int, cb *Callbacks) (pos Pos, found bool, err error) {
+	st := s.init(cb, from)
+	return scanObject(data, from, st.stack[:0], cb, st)
+}
+
+// ScanArray is ScanArray, with the memory of the scanner.
+func (s *Scanner) ScanArray(data []byte, from int, cb *Callbacks) (pos Pos, found bool, err error) {
+	st := s.init(cb, from)
+	return scanArray(data, from, st.stack[:0], cb, st)
+}
+
+func (s *Scanner) init(cb *Callbacks, from int) *scanState {
+	s.st = scanState{start: from, reuse: true, stack: s.st.stack, dups: s.st.dups}
+	if cb != nil {
+		s.st.Limits = cb.Limits
+	}
+	return &s.st
+}
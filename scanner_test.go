@@ -0,0 +1,119 @@
+package flatjson
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// raws of the object in data, as scanned by scan.
+func raws(t *testing.T, data []byte, cb Callbacks, scan func([]byte, int, *Callbacks) (Pos, bool, error)) []string {
+	t.Helper()
+	var got []string
+	cb.OnRaw = func(prefixes Prefixes, name Prefix, value Pos) {
+		path := prefixes.AsString(data)
+		got = append(got, fmt.Sprintf("%s/%s=%s", path, name.String(data), value.Bytes(data)))
+	}
+	if _, _, err := scan(data, 0, &cb); err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestScanner(t *testing.T) {
+	docs := []string{
+		`{"a":[1,{"b":[2,[3,{"c":{"d":4}}]]}],"e":"f"}`,
+		`{"a":1}`,
+		`{"k1":1,"k2":2,"k3":3,"k4":4,"k5":5,"k6":6,"k7":7,"k8":8,"k9":9,"k1":10,"x":{"y":1,"y":2}}`,
+		`{"a":` + strings.Repeat("[", 20) + "1" + strings.Repeat("]", 20) + `}`,
+		`{"k1":0,"k2":0,"k3":0,"k4":0,"k5":0,"k6":0,"k7":0,"k8":0,"k9":0,"k2":1}`,
+	}
+	var s Scanner
+	for _, policy := range []DuplicateKeyPolicy{DuplicateKeysAllow, DuplicateKeysFirstWins, DuplicateKeysLastWins} {
+		for _, doc := range docs {
+			data := []byte(doc)
+			cb := Callbacks{MaxDepth: 99, DuplicateKeys: policy}
+			want := raws(t, data, cb, ScanObject)
+			got := raws(t, data, cb, s.ScanObject)
+			if strings.Join(want, " ") != strings.Join(got, " ") {
+				t.Errorf("policy %d: want %q", policy, want)
+				t.Errorf("policy %d:  got %q", policy, got)
+			}
+		}
+	}
+
+	arr := []byte(`[{"a":[1]},2]`)
+	want := raws(t, arr, Callbacks{MaxDepth: 9}, ScanArray)
+	got := raws(t, arr, Callbacks{MaxDepth: 9}, s.ScanArray)
+	if strings.Join(want, " ") != strings.Join(got, " ") {
+		t.Errorf("want %q", want)
+		t.Errorf(" got %q", got)
+	}
+}
+
+func TestScannerLimits(t *testing.T) {
+	var s Scanner
+	data := []byte(`{"a":{"b":{"c":1}}}`)
+	_, _, err := s.ScanObject(data, 0, &Callbacks{Limits: Limits{MaxDepth: 2}})
+	serr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("want a syntax error, got %v", err)
+	}
+	if serr.Code != ErrCodeTooDeep {
+		t.Errorf("want code %d, got %d", ErrCodeTooDeep, serr.Code)
+	}
+	// the limits of a scan don't carry over to the next
+	if _, _, err := s.ScanObject(data, 0, &Callbacks{}); err != nil {
+		t.Errorf("want no error, got %v", err)
+	}
+}
+
+func TestScannerPool(t *testing.T) {
+	pool := sync.Pool{New: func() any { return new(Scanner) }}
+	data := []byte(`{"a":{"b":[1,2]},"c":{"d":{"e":3}}}`)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				s := pool.Get().(*Scanner)
+				var n int
+				_, _, err := s.ScanObject(data, 0, &Callbacks{
+					MaxDepth:  9,
+					OnInteger: func(Prefixes, Integer) { n++ },
+				})
+				s.Reset()
+				pool.Put(s)
+				if err != nil || n != 3 {
+					t.Errorf("want 3 integers, got %d (%v)", n, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestScannerDoesntAllocate(t *testing.T) {
+	docs := [][]byte{
+		[]byte(`{"a":[1,{"b":[2,[3,{"c":{"d":4}}]]}],"e":"f"}`),
+		[]byte(`{"k1":1,"k2":2,"k3":3,"k4":4,"k5":5,"k6":6,"k7":7,"k8":8,"k9":9,"k1":10,"x":{"y":1,"y":2}}`),
+	}
+	for _, policy := range []DuplicateKeyPolicy{DuplicateKeysAllow, DuplicateKeysFirstWins, DuplicateKeysLastWins} {
+		var s Scanner
+		cb := &Callbacks{MaxDepth: 99, DuplicateKeys: policy, OnRaw: func(Prefixes, Prefix, Pos) {}}
+		allocs := testing.AllocsPerRun(100, func() {
+			for _, data := range docs {
+				if _, _, err := s.ScanObject(data, 0, cb); err != nil {
+					t.Fatal(err)
+				}
+			}
+			s.Reset()
+		})
+		if allocs != 0 {
+			t.Errorf("policy %d: want no allocation, got %v", policy, allocs)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package flatjson
+
+// skipContainer finds the end of the object or array opening at i,
+// without looking at its contents any more than it needs to: it only
+// keeps track of string boundaries and bracket depth. It returns the
+// offset one past the closing bracket.
+//
+// Unlike scanObject and scanArray, it doesn't validate the contents of
+// the container.
+func skipContainer(data []byte, i int) (int, error) {
+	start := i
+	// `kinds` remembers whether each open bracket is square (bit set)
+	// or curly (bit clear), as long as the container never nests more
+	// than 64 deep. Past that, only the depth is tracked.
+	var kinds uint64
+	depth, peak := 0, 0
+	for i < len(data) {
+		i = indexContainerSpecial(data, i)
+		if i >= len(data) {
+			break
+		}
+		switch b := data[i]; b {
+		case '"':
+			pos, err := scanString(data, i)
+			if err != nil {
+				return i, syntaxErr(i, beginStringValueButError, err.(*SyntaxError))
+			}
+			i = pos.To
+			continue
+		case '{', '[':
+			kinds <<= 1
+			if b == '[' {
+				kinds |= 1
+			}
+			depth++
+			peak = max(peak, depth)
+		case '}', ']':
+			isSquare := b == ']'
+			if peak <= 64 && (kinds&1 == 1) != isSquare {
+				return i, syntaxErr(i, expectValueButNoKnownType, nil)
+			}
+			kinds >>= 1
+			depth--
+			if depth == 0 {
+				return i + 1, nil
+			}
+		}
+		i++
+	}
+	if data[start] == '[' {
+		return i, syntaxErr(i, endOfDataNoClosingSquareBracket, nil)
+	}
+	return i, syntaxErr(i, endOfDataNoClosingBracket, nil)
+}
+
+// skipValue finds the end of the value starting at i, and returns the
+// offset one past it. Scalars are validated, but the contents of
+// objects and arrays are not; see skipContainer.
+func skipValue(data []byte, i int) (EntityType, int, error) {
+	if i >= len(data) {
+		return EntityType_Invalid, i, syntaxErr(i, endOfDataNoValue, nil)
+	}
+	et := GuessNextEntityType(data, i)
+	switch et {
+	case EntityType_String:
+		pos, err := scanString(data, i)
+		if err != nil {
+			return et, i, syntaxErr(i, beginStringValueButError, err.(*SyntaxError))
+		}
+		return et, pos.To, nil
+	case EntityType_Number:
+		_, _, _, j, err := scanNumber(data, i)
+		if err != nil {
+			return et, i, syntaxErr(i, beginNumberValueButError, err.(*SyntaxError))
+		}
+		return et, j, nil
+	case EntityType_Boolean_True, EntityType_Null:
+		return et, i + 4, nil
+	case EntityType_Boolean_False:
+		return et, i + 5, nil
+	case EntityType_Object, EntityType_Array:
+		j, err := skipContainer(data, i)
+		return et, j, err
+	}
+	return et, i, syntaxErr(i, expectValueButNoKnownType, nil)
+}
+
+// member is a name/value pair of an object, or an element of an array.
+type member struct {
+	name  Prefix
+	value Pos
+}
+
+// from is where the member begins: its name in an object, its value in
+// an array.
+func (m member) from() int {
+	if m.name.IsObjectKey() {
+		return m.name.from
+	}
+	return m.value.From
+}
+
+// scanMembers calls fn on each member of the object or array starting
+// at i, without looking into their values. It stops early if fn returns
+// false. It returns the position of the whole container if it got to
+// its end.
+func scanMembers(data []byte, i int, fn func(m member) bool) (Pos, error) {
+	start := i
+	isObject := data[i] == '{'
+	i = skipWhitespace(data, i+1)
+	if i < len(data) && (data[i] == '}' || data[i] == ']') {
+		if bracketsMatch(data[start], data[i]) {
+			return Pos{start, i + 1}, nil
+		}
+		return Pos{}, syntaxErr(i, expectValueButNoKnownType, nil)
+	}
+	for index := 0; ; index++ {
+		if i >= len(data) {
+			return Pos{}, syntaxErr(i, endOfDataNoValue, nil)
+		}
+		var (
+			m   member
+			err error
+		)
+		if isObject {
+			if m.name, i, err = scanPairName(data, i); err != nil {
+				return Pos{}, err
+			}
+		} else {
+			m.name = newArrayIndexPrefix(index)
+		}
+		m.value.From = i
+		if _, m.value.To, err = skipValue(data, i); err != nil {
+			return Pos{}, err
+		}
+		if !fn(m) {
+			return Pos{}, nil
+		}
+		i = skipWhitespace(data, m.value.To)
+		if i >= len(data) {
+			if isObject {
+				return Pos{}, syntaxErr(i, endOfDataNoClosingBracket, nil)
+			}
+			return Pos{}, syntaxErr(i, endOfDataNoClosingSquareBracket, nil)
+		}
+		switch b := data[i]; {
+		case b == ',':
+			i = skipWhitespace(data, i+1)
+		case isObject && b == '}', !isObject && b == ']':
+			return Pos{start, i + 1}, nil
+		case isObject:
+			return Pos{}, syntaxErr(i, expectCommaOrClosingBracket, nil)
+		default:
+			return Pos{}, syntaxErr(i, expectCommaOrClosingSquareBracket, nil)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package flatjson
+
+import (
+	"testing"
+)
+
+func TestSkipContainer(t *testing.T) {
+	tests := []struct {
+		Name string
+		Data string
+
+		WantEnd int
+
+		WantErrError  string
+		WantErrOffset int
+	}{
+		{Name: "empty object", Data: `{}`, WantEnd: 2},
+		{Name: "empty array", Data: `[]`, WantEnd: 2},
+		{Name: "garbage after", Data: `{"a":1} }}]]`, WantEnd: 7},
+		{Name: "nested", Data: `{"a":[1,{"b":[[],{}]}],"c":{}}`, WantEnd: 30},
+		{Name: "brackets in strings", Data: `["}]", "\"]", "\\"]`, WantEnd: 19},
+		{Name: "contents aren't validated", Data: `{"a" 1 2 3 lol}`, WantEnd: 15},
+		{Name: "deeper than 64", Data: deepArray(100), WantEnd: 200},
+
+		{
+			Name:          "mismatched brackets",
+			Data:          `[}`,
+			WantErrError:  expectValueButNoKnownType,
+			WantErrOffset: 1,
+		},
+		{
+			Name:          "unclosed object",
+			Data:          `{"a":[]`,
+			WantErrError:  endOfDataNoClosingBracket,
+			WantErrOffset: 7,
+		},
+		{
+			Name:          "unclosed array",
+			Data:          `[{}`,
+			WantErrError:  endOfDataNoClosingSquareBracket,
+			WantErrOffset: 3,
+		},
+		{
+			Name:          "unterminated string",
+			Data:          `["]`,
+			WantErrError:  beginStringValueButError + ", " + reachedEndScanningCharacters,
+			WantErrOffset: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			end, err := skipContainer([]byte(tt.Data), 0)
+			if tt.WantErrError != "" && err == nil {
+				t.Errorf("want an error, got none")
+			} else if tt.WantErrError != "" && err != nil {
+				gotErr := err.(*SyntaxError)
+				if want, got := tt.WantErrOffset, gotErr.Offset; want != got {
+					t.Errorf("want err offset %d, was %d", want, got)
+				}
+				if want, got := tt.WantErrError, gotErr.Error(); want != got {
+					t.Errorf("want error: %q", want)
+					t.Errorf(" got error: %q", got)
+				}
+			} else if err != nil {
+				t.Error(err)
+			} else if want, got := tt.WantEnd, end; want != got {
+				t.Errorf("want end %d, got %d", want, got)
+			}
+		})
+	}
+}
+
+func TestScanObjectSkipsBeyondMaxDepth(t *testing.T) {
+	// the inner object is malformed, but it's deeper than MaxDepth so
+	// it's never looked at
+	data := []byte(`{"a":1,"b":{"c": 1 2 3},"d":[tru]}`)
+	var raws []string
+	pos, found, err := ScanObject(data, 0, &Callbacks{
+		OnRaw: func(_ Prefixes, name Prefix, value Pos) {
+			raws = append(raws, name.String(data)+"="+value.String(data))
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || pos != (Pos{0, len(data)}) {
+		t.Errorf("want to find the whole object, got %+v (%v)", pos, found)
+	}
+	want := []string{`"a"=1`, `"b"={"c": 1 2 3}`, `"d"=[tru]`}
+	if len(want) != len(raws) {
+		t.Fatalf("want %q, got %q", want, raws)
+	}
+	for i := range want {
+		if want[i] != raws[i] {
+			t.Errorf("want %q, got %q", want[i], raws[i])
+		}
+	}
+}
+
+func deepArray(depth int) string {
+	b := make([]byte, 0, 2*depth)
+	for i := 0; i < depth; i++ {
+		b = append(b, '[')
+	}
+	for i := 0; i < depth; i++ {
+		b = append(b, ']')
+	}
+	return string(b)
+}
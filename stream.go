@@ -0,0 +1,96 @@
+package flatjson
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+)
+
+// docReader reads a stream of JSON documents, such as NDJSON: documents
+// separated by whitespace. Documents may span many lines.
+type docReader struct {
+	ctx context.Context
+	r   *bufio.Reader
+	eof bool
+
+	buf []byte
+	// start of the unread part of buf
+	off int
+	// offset in the stream of the start of buf
+	base int64
+}
+
+func newDocReader(ctx context.Context, r io.Reader) *docReader {
+	return &docReader{ctx: ctx, r: bufio.NewReaderSize(r, 64<<10)}
+}
+
+// next returns the next document of the stream and its offset in the
+// stream. The document is only valid until the next call. At the end of
+// the stream, it returns io.EOF. Once the context is done, it returns a
+// *ContextError.
+func (dr *docReader) next() ([]byte, int64, error) {
+	for {
+		select {
+		case <-dr.ctx.Done():
+			offset := dr.base + int64(dr.off)
+			return nil, offset, &ContextError{Offset: offset, Err: dr.ctx.Err()}
+		default:
+		}
+		i := skipWhitespace(dr.buf, dr.off)
+		if i < len(dr.buf) {
+			_, end, err := skipValue(dr.buf, i)
+			if err == nil && (end < len(dr.buf) || dr.eof) {
+				dr.off = end
+				return dr.buf[i:end], dr.base + int64(i), nil
+			}
+			if err != nil && (dr.eof || !atEndOfData(err, len(dr.buf))) {
+				dr.off = len(dr.buf)
+				return nil, dr.base + int64(i), err
+			}
+		} else if dr.eof {
+			return nil, dr.base + int64(i), io.EOF
+		}
+		if err := dr.fill(); err != nil {
+			return nil, dr.base + int64(dr.off), err
+		}
+	}
+}
+
+// fill reads one more line into the buffer, dropping what was already
+// read.
+func (dr *docReader) fill() error {
+	if dr.off > 0 {
+		n := copy(dr.buf, dr.buf[dr.off:])
+		dr.base += int64(dr.off)
+		dr.buf, dr.off = dr.buf[:n], 0
+	}
+	for {
+		line, err := dr.r.ReadSlice('\n')
+		dr.buf = append(dr.buf, line...)
+		switch {
+		case err == nil:
+			return nil
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case errors.Is(err, io.EOF):
+			dr.eof = true
+			return nil
+		default:
+			return err
+		}
+	}
+}
+
+// atEndOfData tells if a syntax error was caused by reaching the end of
+// n bytes of data, so that more data could fix it.
+func atEndOfData(err error, n int) bool {
+	var serr *SyntaxError
+	if !errors.As(err, &serr) {
+		return false
+	}
+	for serr.SubErr != nil {
+		serr = serr.SubErr
+	}
+	return serr.Offset >= n-1
+}
@@ -0,0 +1,115 @@
+package flatjson
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// SWAR ("SIMD within a register") helpers. They look at 8 bytes of
+// input at a time using plain integer arithmetic, which lets the hot
+// loops of the scanner skip over long runs of whitespace and string
+// content without assembly or cgo.
+
+const (
+	swarLo7  = 0x7f7f7f7f7f7f7f7f
+	swarHigh = 0x8080808080808080
+
+	swarQuote     = 0x2222222222222222 // '"'
+	swarBackslash = 0x5c5c5c5c5c5c5c5c // '\\'
+	swarCtrl      = 0xe0e0e0e0e0e0e0e0 // bits that are all zero for bytes < 0x20
+
+	swarOpenCurly   = 0x7b7b7b7b7b7b7b7b // '{'
+	swarCloseCurly  = 0x7d7d7d7d7d7d7d7d // '}'
+	swarOpenSquare  = 0x5b5b5b5b5b5b5b5b // '['
+	swarCloseSquare = 0x5d5d5d5d5d5d5d5d // ']'
+
+	swarSpace = 0x2020202020202020 // ' '
+	swarTab   = 0x0909090909090909 // '\t'
+	swarLF    = 0x0a0a0a0a0a0a0a0a // '\n'
+	swarCR    = 0x0d0d0d0d0d0d0d0d // '\r'
+)
+
+// swarZeroBytes sets the high bit of every byte of x that is zero, and
+// clears every other bit. Unlike the classic `(x-lo)&^x&hi` trick, it
+// has no false positives, so every flagged byte can be trusted.
+func swarZeroBytes(x uint64) uint64 {
+	return ^(((x & swarLo7) + swarLo7) | x | swarLo7)
+}
+
+// swarStringSpecial flags the bytes of x that end a run of plain string
+// content: a quote, a backslash or a control character.
+func swarStringSpecial(x uint64) uint64 {
+	return swarZeroBytes(x^swarQuote) |
+		swarZeroBytes(x^swarBackslash) |
+		swarZeroBytes(x&swarCtrl)
+}
+
+// swarContainerSpecial flags the bytes of x that matter when skipping
+// over a container: quotes and brackets.
+func swarContainerSpecial(x uint64) uint64 {
+	return swarZeroBytes(x^swarQuote) |
+		swarZeroBytes(x^swarOpenCurly) |
+		swarZeroBytes(x^swarCloseCurly) |
+		swarZeroBytes(x^swarOpenSquare) |
+		swarZeroBytes(x^swarCloseSquare)
+}
+
+// swarWhitespace flags the bytes of x that are JSON whitespace.
+func swarWhitespace(x uint64) uint64 {
+	return swarZeroBytes(x^swarSpace) |
+		swarZeroBytes(x^swarTab) |
+		swarZeroBytes(x^swarLF) |
+		swarZeroBytes(x^swarCR)
+}
+
+// indexStringSpecial returns the index of the first quote, backslash or
+// control character found in data at or after i, or len(data) if there
+// is none.
+func indexStringSpecial(data []byte, i int) int {
+	for ; i+8 <= len(data); i += 8 {
+		if m := swarStringSpecial(binary.LittleEndian.Uint64(data[i:])); m != 0 {
+			return i + bits.TrailingZeros64(m)>>3
+		}
+	}
+	for ; i < len(data); i++ {
+		if b := data[i]; b == '"' || b == '\\' || b < 0x20 {
+			return i
+		}
+	}
+	return i
+}
+
+// indexContainerSpecial returns the index of the first quote or bracket
+// found in data at or after i, or len(data) if there is none.
+func indexContainerSpecial(data []byte, i int) int {
+	if useSWAR {
+		for ; i+8 <= len(data); i += 8 {
+			if m := swarContainerSpecial(binary.LittleEndian.Uint64(data[i:])); m != 0 {
+				return i + bits.TrailingZeros64(m)>>3
+			}
+		}
+	}
+	for ; i < len(data); i++ {
+		switch data[i] {
+		case '"', '{', '}', '[', ']':
+			return i
+		}
+	}
+	return i
+}
+
+// indexNonWhitespace returns the index of the first non-whitespace
+// character found in data at or after i, or len(data) if there is none.
+func indexNonWhitespace(data []byte, i int) int {
+	for ; i+8 <= len(data); i += 8 {
+		if m := ^swarWhitespace(binary.LittleEndian.Uint64(data[i:])) & swarHigh; m != 0 {
+			return i + bits.TrailingZeros64(m)>>3
+		}
+	}
+	for ; i < len(data); i++ {
+		if b := data[i]; b != ' ' && b != '\t' && b != '\n' && b != '\r' {
+			return i
+		}
+	}
+	return i
+}
@@ -0,0 +1,7 @@
+//go:build flatjson_noswar
+
+package flatjson
+
+// useSWAR selects the word-at-a-time scanning loops. Build without the
+// `flatjson_noswar` tag to enable them.
+const useSWAR = false
@@ -0,0 +1,7 @@
+//go:build !flatjson_noswar
+
+package flatjson
+
+// useSWAR selects the word-at-a-time scanning loops. Build with the
+// `flatjson_noswar` tag to fall back to the byte-at-a-time loops.
+const useSWAR = true
@@ -0,0 +1,101 @@
+package flatjson
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestSWARZeroBytes(t *testing.T) {
+	for b := 0; b < 256; b++ {
+		for _, other := range []byte{0x00, 0x01, 0x7f, 0x80, 0xff} {
+			x := uint64(b) | uint64(other)<<8 | uint64(b)<<16 | uint64(other)<<56
+			got := swarZeroBytes(x)
+			for k := 0; k < 8; k++ {
+				isZero := byte(x>>(8*k)) == 0
+				flagged := byte(got>>(8*k)) == 0x80
+				if isZero != flagged {
+					t.Fatalf("x=%#016x byte %d: zero=%v but flagged=%v", x, k, isZero, flagged)
+				}
+				if v := byte(got >> (8 * k)); v != 0 && v != 0x80 {
+					t.Fatalf("x=%#016x byte %d: unexpected bits %#02x", x, k, v)
+				}
+			}
+		}
+	}
+}
+
+func TestScanStringLongRuns(t *testing.T) {
+	long := bytes.Repeat([]byte("abcdefgh"), 9)
+	tests := []string{
+		`"` + string(long) + `"`,
+		`"` + string(long) + `\"` + string(long) + `"`,
+		`"` + string(long) + `é` + string(long[:3]) + `"`,
+		`"` + string(long) + "\x01" + string(long[:5]) + `"`,
+		`"` + string(long) + `\u00` + string(long[:5]) + `"`,
+		`"` + string(long),
+		`"` + string(long) + `\`,
+	}
+	for _, data := range tests {
+		want, wantErr := scanStringBytewise([]byte(data), 0)
+		got, gotErr := scanString([]byte(data), 0)
+		if want != got || !reflect.DeepEqual(wantErr, gotErr) {
+			t.Errorf("%q: want %+v (%v), got %+v (%v)", data, want, wantErr, got, gotErr)
+		}
+	}
+}
+
+func FuzzScanString(f *testing.F) {
+	f.Add([]byte(`"once upon a time"`), 0)
+	f.Add([]byte(`"\ \" \\ \/ \b \f \n \r \t ᄑ " hjbjhbjkhbehjwb`), 0)
+	f.Add([]byte(`" lol \u333R "`), 0)
+	f.Add([]byte(`{"hello":"world, this is a longer string value"}`), 9)
+	f.Fuzz(func(t *testing.T, data []byte, from int) {
+		if from < 0 || from >= len(data) {
+			return
+		}
+		want, wantErr := scanStringBytewise(data, from)
+		got, gotErr := scanString(data, from)
+		if want != got {
+			t.Fatalf("want pos %+v, got %+v", want, got)
+		}
+		if !reflect.DeepEqual(wantErr, gotErr) {
+			t.Fatalf("want err %v, got %v", wantErr, gotErr)
+		}
+	})
+}
+
+func FuzzSkipWhitespace(f *testing.F) {
+	f.Add([]byte(" \r \n \t hello  \r \n \t hello"), 0)
+	f.Add([]byte("                \t\t\t\t\n\n\n\r\r\r\x01"), 0)
+	f.Add([]byte("hello"), 2)
+	f.Fuzz(func(t *testing.T, data []byte, from int) {
+		if from < 0 || from > len(data)+1 {
+			return
+		}
+		want := skipWhitespaceBytewise(data, from)
+		if got := skipWhitespace(data, from); want != got {
+			t.Fatalf("want advance to %d, got %d", want, got)
+		}
+	})
+}
+
+func BenchmarkScanString(b *testing.B) {
+	data := []byte(`"` + string(bytes.Repeat([]byte("a log message, "), 64)) + `"`)
+	b.Run("swar", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for b.Loop() {
+			if _, err := scanString(data, 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("bytewise", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for b.Loop() {
+			if _, err := scanStringBytewise(data, 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
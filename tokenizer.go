@@ -0,0 +1,351 @@
+package flatjson
+
+import "io"
+
+// TokenKind is what a Token is.
+type TokenKind uint8
+
+const (
+	TokenInvalid TokenKind = iota
+	TokenBeginObject
+	TokenEndObject
+	TokenBeginArray
+	TokenEndArray
+	TokenKey
+	TokenString
+	TokenNumber
+	TokenTrue
+	TokenFalse
+	TokenNull
+)
+
+var tokenKindNames = [...]string{
+	TokenInvalid:     "invalid",
+	TokenBeginObject: "begin object",
+	TokenEndObject:   "end object",
+	TokenBeginArray:  "begin array",
+	TokenEndArray:    "end array",
+	TokenKey:         "key",
+	TokenString:      "string",
+	TokenNumber:      "number",
+	TokenTrue:        "true",
+	TokenFalse:       "false",
+	TokenNull:        "null",
+}
+
+func (k TokenKind) String() string {
+	if int(k) < len(tokenKindNames) {
+		return tokenKindNames[k]
+	}
+	return tokenKindNames[TokenInvalid]
+}
+
+// Token is a piece of a JSON document, as found by a Tokenizer.
+type Token struct {
+	Kind TokenKind
+	// Pos of the token in the data: the bracket of the beginning or end
+	// of an object or array, a quoted key without its colon, or a whole
+	// string, number, boolean or null.
+	Pos Pos
+	// Depth is how many objects and arrays enclose the token. The
+	// brackets of an object or array are at the depth of its parent.
+	Depth int
+}
+
+// tokExpect is what a Tokenizer expects next.
+type tokExpect uint8
+
+const (
+	// a value at the top level, or an element after a comma
+	expectValue tokExpect = iota
+	// the first element of an array, or its end
+	expectFirstElement
+	// the first key of an object, or its end
+	expectFirstKey
+	// a key after a comma
+	expectKey
+	// the colon after a key, then its value
+	expectColon
+	// a comma, or the end of the object or array
+	expectCommaOrEnd
+)
+
+// Tokenizer splits JSON data into tokens, one at a time, checking that
+// they follow each other as they should. Many documents can follow
+// each other in the data, as in a stream.
+//
+// A Tokenizer can be reused with Reset; once its stack has grown to the
+// depth of the documents it reads, it allocates nothing.
+type Tokenizer struct {
+	// UTF8 is what to do with keys and strings that aren't valid UTF-8.
+	// With UTF8Error, Next fails at the first invalid byte. Reset keeps
+	// it.
+	UTF8 UTF8Policy
+
+	data   []byte
+	i      int
+	expect tokExpect
+	// offset of the opening bracket of each enclosing object or array
+	stack []int
+	// err is the syntax error that stopped the tokenizer, if any
+	err error
+
+	// peeked tells if peek holds the next token, and after the state
+	// of the tokenizer once it's read
+	peeked  bool
+	peek    Token
+	peekErr error
+	after   tokenizerState
+}
+
+type tokenizerState struct {
+	i      int
+	expect tokExpect
+	depth  int
+	err    error
+}
+
+// NewTokenizer returns a Tokenizer of the data.
+func NewTokenizer(data []byte) *Tokenizer {
+	t := new(Tokenizer)
+	t.Reset(data)
+	return t
+}
+
+// Reset makes the tokenizer start over on data, reusing its memory.
+func (t *Tokenizer) Reset(data []byte) {
+	*t = Tokenizer{UTF8: t.UTF8, data: data, stack: t.stack[:0]}
+}
+
+// Next token of the data. At the end of the data, between documents,
+// it returns io.EOF. Errors are *SyntaxError, and the tokenizer stops
+// at the first one.
+func (t *Tokenizer) Next() (Token, error) {
+	if t.peeked {
+		t.peeked = false
+		t.restore(t.after)
+		return t.peek, t.peekErr
+	}
+	return t.next()
+}
+
+// Peek is the token Next will return, without moving past it.
+func (t *Tokenizer) Peek() (Token, error) {
+	if !t.peeked {
+		before := t.save()
+		t.peek, t.peekErr = t.next()
+		t.after = t.save()
+		t.restore(before)
+		t.peeked = true
+	}
+	return t.peek, t.peekErr
+}
+
+// Skip jumps over what's left of the innermost object or array, its end
+// included, so that the next token is the one after it. Right after a
+// TokenBeginObject or TokenBeginArray, that's the whole object or array.
+// At the top level, Skip jumps over the next document.
+//
+// The contents of what's skipped are only checked as skipValue does.
+func (t *Tokenizer) Skip() error {
+	t.peeked = false
+	if t.err != nil {
+		return t.err
+	}
+	depth := len(t.stack)
+	if depth == 0 {
+		i := skipWhitespace(t.data, t.i)
+		if i >= len(t.data) {
+			t.i = i
+			return io.EOF
+		}
+		_, j, err := skipValue(t.data, i)
+		if err != nil {
+			return t.fail(err)
+		}
+		t.i = j
+		return nil
+	}
+	end, err := skipContainer(t.data, t.stack[depth-1])
+	if err != nil {
+		return t.fail(err)
+	}
+	t.stack = t.stack[:depth-1]
+	t.i = end
+	t.afterValue()
+	return nil
+}
+
+func (t *Tokenizer) save() tokenizerState {
+	return tokenizerState{i: t.i, expect: t.expect, depth: len(t.stack), err: t.err}
+}
+
+// restore goes back to a state saved before or after a call to next,
+// which only ever pushes or pops a single element of the stack: what
+// was there is still in its backing array.
+func (t *Tokenizer) restore(s tokenizerState) {
+	t.i, t.expect, t.stack, t.err = s.i, s.expect, t.stack[:s.depth], s.err
+}
+
+func (t *Tokenizer) fail(err error) error {
+	t.err = err
+	return err
+}
+
+func (t *Tokenizer) next() (Token, error) {
+	if t.err != nil {
+		return Token{}, t.err
+	}
+	data := t.data
+	i := skipWhitespace(data, t.i)
+	depth := len(t.stack)
+
+	switch t.expect {
+	case expectCommaOrEnd:
+		isObject := data[t.stack[depth-1]] == '{'
+		if i >= len(data) {
+			if isObject {
+				return Token{}, t.fail(syntaxErr(i, endOfDataNoClosingBracket, nil))
+			}
+			return Token{}, t.fail(syntaxErr(i, endOfDataNoClosingSquareBracket, nil))
+		}
+		switch data[i] {
+		case ',':
+			i = skipWhitespace(data, i+1)
+			if isObject {
+				return t.key(i, depth)
+			}
+			return t.value(i, depth)
+		case '}':
+			if isObject {
+				return t.end(i, depth, TokenEndObject)
+			}
+		case ']':
+			if !isObject {
+				return t.end(i, depth, TokenEndArray)
+			}
+		}
+		if isObject {
+			return Token{}, t.fail(syntaxErr(i, expectCommaOrClosingBracket, nil))
+		}
+		return Token{}, t.fail(syntaxErr(i, expectCommaOrClosingSquareBracket, nil))
+
+	case expectFirstKey:
+		if i < len(data) && data[i] == '}' {
+			return t.end(i, depth, TokenEndObject)
+		}
+		return t.key(i, depth)
+
+	case expectKey:
+		return t.key(i, depth)
+
+	case expectFirstElement:
+		if i < len(data) && data[i] == ']' {
+			return t.end(i, depth, TokenEndArray)
+		}
+
+	case expectColon:
+		j, err := scanSeparator(data, i)
+		if err != nil {
+			return Token{}, t.fail(err)
+		}
+		i = j
+
+	case expectValue:
+		if i >= len(data) && depth == 0 {
+			t.i = i
+			return Token{}, io.EOF
+		}
+	}
+	return t.value(i, depth)
+}
+
+func (t *Tokenizer) key(i, depth int) (Token, error) {
+	data := t.data
+	if i >= len(data) {
+		return Token{}, t.fail(syntaxErr(i, endOfDataNoNamePair, nil))
+	}
+	if data[i] != '"' {
+		return Token{}, t.fail(syntaxErr(i, expectingNameBeforeValue, nil))
+	}
+	pos, err := scanString(data, i)
+	if err != nil {
+		return Token{}, t.fail(syntaxErr(i, expectingNameBeforeValue, err.(*SyntaxError)))
+	}
+	if err := t.checkUTF8(pos); err != nil {
+		return Token{}, t.fail(err)
+	}
+	t.i, t.expect = pos.To, expectColon
+	return Token{Kind: TokenKey, Pos: pos, Depth: depth}, nil
+}
+
+func (t *Tokenizer) value(i, depth int) (Token, error) {
+	data := t.data
+	if i >= len(data) {
+		return Token{}, t.fail(syntaxErr(i, endOfDataNoValue, nil))
+	}
+	tok := Token{Pos: Pos{From: i}, Depth: depth}
+	switch GuessNextEntityType(data, i) {
+	case EntityType_String:
+		pos, err := scanString(data, i)
+		if err != nil {
+			return Token{}, t.fail(syntaxErr(i, beginStringValueButError, err.(*SyntaxError)))
+		}
+		if err := t.checkUTF8(pos); err != nil {
+			return Token{}, t.fail(err)
+		}
+		tok.Kind, tok.Pos = TokenString, pos
+	case EntityType_Number:
+		_, _, _, j, err := scanNumber(data, i)
+		if err != nil {
+			return Token{}, t.fail(syntaxErr(i, beginNumberValueButError, err.(*SyntaxError)))
+		}
+		tok.Kind, tok.Pos.To = TokenNumber, j
+	case EntityType_Boolean_True:
+		tok.Kind, tok.Pos.To = TokenTrue, i+4
+	case EntityType_Boolean_False:
+		tok.Kind, tok.Pos.To = TokenFalse, i+5
+	case EntityType_Null:
+		tok.Kind, tok.Pos.To = TokenNull, i+4
+	case EntityType_Object:
+		t.stack = append(t.stack, i)
+		t.i, t.expect = i+1, expectFirstKey
+		return Token{Kind: TokenBeginObject, Pos: Pos{From: i, To: i + 1}, Depth: depth}, nil
+	case EntityType_Array:
+		t.stack = append(t.stack, i)
+		t.i, t.expect = i+1, expectFirstElement
+		return Token{Kind: TokenBeginArray, Pos: Pos{From: i, To: i + 1}, Depth: depth}, nil
+	default:
+		return Token{}, t.fail(syntaxErr(i, expectValueButNoKnownType, nil))
+	}
+	t.i = tok.Pos.To
+	t.afterValue()
+	return tok, nil
+}
+
+// checkUTF8 enforces the UTF8 policy on the key or string at pos.
+func (t *Tokenizer) checkUTF8(pos Pos) error {
+	if t.UTF8 != UTF8Error {
+		return nil
+	}
+	if i := indexInvalidUTF8(pos.Bytes(t.data)); i >= 0 {
+		return syntaxErr(pos.From+i, invalidUTF8InString, nil)
+	}
+	return nil
+}
+
+// end closes the innermost object or array at i.
+func (t *Tokenizer) end(i, depth int, kind TokenKind) (Token, error) {
+	t.stack = t.stack[:depth-1]
+	t.i = i + 1
+	t.afterValue()
+	return Token{Kind: kind, Pos: Pos{From: i, To: i + 1}, Depth: depth - 1}, nil
+}
+
+func (t *Tokenizer) afterValue() {
+	if len(t.stack) == 0 {
+		t.expect = expectValue
+	} else {
+		t.expect = expectCommaOrEnd
+	}
+}
@@ -0,0 +1,325 @@
+package flatjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// tokens of the data, each as its depth, kind and bytes.
+func tokens(t *testing.T, data []byte) []string {
+	t.Helper()
+	var got []string
+	tkz := NewTokenizer(data)
+	for {
+		tok, err := tkz.Next()
+		if err == io.EOF {
+			return got
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%d %v %s", tok.Depth, tok.Kind, tok.Pos.Bytes(data)))
+	}
+}
+
+func TestTokenizer(t *testing.T) {
+	tests := []struct {
+		Name string
+		Data string
+		Want []string
+	}{
+		{
+			Name: "nothing",
+			Data: " \n ",
+		},
+		{
+			Name: "scalars",
+			Data: `"a" -1.5e3 true false null`,
+			Want: []string{`0 string "a"`, `0 number -1.5e3`, `0 true true`, `0 false false`, `0 null null`},
+		},
+		{
+			Name: "empty containers",
+			Data: `{ } [ ]`,
+			Want: []string{`0 begin object {`, `0 end object }`, `0 begin array [`, `0 end array ]`},
+		},
+		{
+			Name: "nested",
+			Data: `{"a" : [1, {"b\"":null}], "c":"d"}`,
+			Want: []string{
+				`0 begin object {`,
+				`1 key "a"`,
+				`1 begin array [`,
+				`2 number 1`,
+				`2 begin object {`,
+				`3 key "b\""`,
+				`3 null null`,
+				`2 end object }`,
+				`1 end array ]`,
+				`1 key "c"`,
+				`1 string "d"`,
+				`0 end object }`,
+			},
+		},
+		{
+			Name: "stream",
+			Data: "{}\n[]{\"a\":1}",
+			Want: []string{
+				`0 begin object {`, `0 end object }`,
+				`0 begin array [`, `0 end array ]`,
+				`0 begin object {`, `1 key "a"`, `1 number 1`, `0 end object }`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			got := tokens(t, []byte(tt.Data))
+			if want := strings.Join(tt.Want, "\n"); want != strings.Join(got, "\n") {
+				t.Errorf("want %q", tt.Want)
+				t.Errorf(" got %q", got)
+			}
+		})
+	}
+}
+
+func TestTokenizerErrors(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Data          string
+		WantErrError  string
+		WantErrOffset int
+	}{
+		{
+			Name:          "missing comma in object",
+			Data:          `{"a":1 "b":2}`,
+			WantErrError:  expectCommaOrClosingBracket,
+			WantErrOffset: 7,
+		},
+		{
+			Name:          "missing comma in array",
+			Data:          `[1 2]`,
+			WantErrError:  expectCommaOrClosingSquareBracket,
+			WantErrOffset: 3,
+		},
+		{
+			Name:          "mismatched end",
+			Data:          `[1}`,
+			WantErrError:  expectCommaOrClosingSquareBracket,
+			WantErrOffset: 2,
+		},
+		{
+			Name:          "trailing comma",
+			Data:          `[1,]`,
+			WantErrError:  expectValueButNoKnownType,
+			WantErrOffset: 3,
+		},
+		{
+			Name:          "missing colon",
+			Data:          `{"a" 1}`,
+			WantErrError:  noColonFound,
+			WantErrOffset: 5,
+		},
+		{
+			Name:          "key isn't a string",
+			Data:          `{1:2}`,
+			WantErrError:  expectingNameBeforeValue,
+			WantErrOffset: 1,
+		},
+		{
+			Name:          "unclosed object",
+			Data:          `{"a":{"b":1}`,
+			WantErrError:  endOfDataNoClosingBracket,
+			WantErrOffset: 12,
+		},
+		{
+			Name:          "missing value",
+			Data:          `{"a":`,
+			WantErrError:  endOfDataNoValueForName,
+			WantErrOffset: 5,
+		},
+		{
+			Name:          "bad number",
+			Data:          `[1.]`,
+			WantErrError:  beginNumberValueButError + ", " + scanningForFraction + ", " + needAtLeastOneDigit,
+			WantErrOffset: 1,
+		},
+		{
+			Name:          "garbage",
+			Data:          `{} x`,
+			WantErrError:  expectValueButNoKnownType,
+			WantErrOffset: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			tkz := NewTokenizer([]byte(tt.Data))
+			var err error
+			for err == nil {
+				_, err = tkz.Next()
+			}
+			gotErr, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("want a syntax error, got %v", err)
+			}
+			if want, got := tt.WantErrOffset, gotErr.Offset; want != got {
+				t.Errorf("want err offset %d, was %d", want, got)
+			}
+			if want, got := tt.WantErrError, gotErr.Error(); want != got {
+				t.Errorf("want error: %q", want)
+				t.Errorf(" got error: %q", got)
+			}
+			if _, again := tkz.Next(); again != err {
+				t.Errorf("want the tokenizer to stay stopped, got %v", again)
+			}
+		})
+	}
+}
+
+func TestTokenizerPeek(t *testing.T) {
+	data := []byte(`{"a":[1],"b":{}}`)
+	tkz := NewTokenizer(data)
+	var got []string
+	for {
+		peeked, perr := tkz.Peek()
+		again, _ := tkz.Peek()
+		tok, err := tkz.Next()
+		if peeked != tok || again != tok || perr != err {
+			t.Fatalf("peeked %v (%v), then got %v (%v)", peeked, perr, tok, err)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, tok.Pos.String(data))
+	}
+	if want := `{ "a" [ 1 ] "b" { } }`; want != strings.Join(got, " ") {
+		t.Errorf("want %s", want)
+		t.Errorf(" got %s", strings.Join(got, " "))
+	}
+}
+
+func TestTokenizerSkip(t *testing.T) {
+	data := []byte(`{"a":{"b":[1,{"c":2}],"d":3},"e":[4,5],"f":6} [7]`)
+	tkz := NewTokenizer(data)
+	var got []string
+	for {
+		tok, err := tkz.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, tok.Pos.String(data))
+		switch tok.Pos.String(data) {
+		case `"b"`:
+			// skips the rest of the object holding "b"
+			if err := tkz.Skip(); err != nil {
+				t.Fatal(err)
+			}
+		case `"e"`:
+			// skips the array right after peeking at it
+			if tok, _ := tkz.Peek(); tok.Kind != TokenBeginArray {
+				t.Fatalf("want the array, got %v", tok.Kind)
+			}
+			if _, err := tkz.Next(); err != nil {
+				t.Fatal(err)
+			}
+			if err := tkz.Skip(); err != nil {
+				t.Fatal(err)
+			}
+		case `}`:
+			// skips the next document
+			if err := tkz.Skip(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if want := `{ "a" { "b" "e" "f" 6 }`; want != strings.Join(got, " ") {
+		t.Errorf("want %s", want)
+		t.Errorf(" got %s", strings.Join(got, " "))
+	}
+	if err := tkz.Skip(); err != io.EOF {
+		t.Errorf("want %v, got %v", io.EOF, err)
+	}
+}
+
+func TestTokenizerMatchesEncodingJSON(t *testing.T) {
+	for _, doc := range []string{
+		`{"a":[1,-2.5e10,{"b":[true,false,null]}],"c":{"d\u00e9\n":"e\"f"}}`,
+		`[[],{},"",0,[[[{"x":{"y":[]}}]]]]`,
+		`{"msg":"GET /index.html","status":200,"ms":0.25,"tags":["a","b"],"user":null}`,
+	} {
+		data := []byte(doc)
+		var want []string
+		dec := json.NewDecoder(strings.NewReader(string(data)))
+		dec.UseNumber()
+		for {
+			tok, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want = append(want, fmt.Sprint(tok))
+		}
+
+		var got []string
+		tkz := NewTokenizer(data)
+		for {
+			tok, err := tkz.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch tok.Kind {
+			case TokenKey, TokenString:
+				s, err := Unquote(tok.Pos.Bytes(data))
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, string(s))
+			case TokenNull:
+				got = append(got, "<nil>")
+			default:
+				got = append(got, tok.Pos.String(data))
+			}
+		}
+		if strings.Join(want, "\n") != strings.Join(got, "\n") {
+			t.Errorf("want %q", want)
+			t.Errorf(" got %q", got)
+		}
+	}
+}
+
+func TestTokenizerDoesntAllocate(t *testing.T) {
+	data := []byte(`{"a":[1,2,{"b":[3,4]}],"c":{"d":"e"},"f":[true,false,null]}`)
+	tkz := NewTokenizer(data)
+	allocs := testing.AllocsPerRun(100, func() {
+		tkz.Reset(data)
+		for {
+			tok, err := tkz.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tok.Kind == TokenBeginArray {
+				if _, err := tkz.Peek(); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("want no allocation, got %v", allocs)
+	}
+}
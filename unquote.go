@@ -1,62 +1,260 @@
 package flatjson
 
 import (
-	"bytes"
-	"fmt"
 	"strconv"
+	"unicode/utf16"
 	"unicode/utf8"
 	"unsafe"
 )
 
-// lifted from https://github.com/iOliverNguyen/ujson/blob/772b59d2318e0b7d164303cf297c35067f24269e/quote.go#L35C1-L88C2
+// SurrogatePolicy is what to do with a `\u` escape of a UTF-16
+// surrogate that isn't part of a pair, which has no meaning in UTF-8.
+type SurrogatePolicy uint8
+
+const (
+	// SurrogateReplace decodes lone surrogates as U+FFFD.
+	SurrogateReplace SurrogatePolicy = iota
+	// SurrogateError fails on lone surrogates.
+	SurrogateError
+)
+
+// UTF8Policy is what to do with strings that aren't valid UTF-8.
+type UTF8Policy uint8
+
+const (
+	// UTF8Replace replaces each invalid byte by U+FFFD when decoding.
+	UTF8Replace UTF8Policy = iota
+	// UTF8Pass keeps invalid bytes as they are.
+	UTF8Pass
+	// UTF8Error fails with a *SyntaxError at the first invalid byte.
+	UTF8Error
+)
+
+// Unquoter decodes JSON strings according to its policies. The zero
+// value is ready to use.
+type Unquoter struct {
+	LoneSurrogates SurrogatePolicy
+	InvalidUTF8    UTF8Policy
+}
+
+const (
+	invalidQuotedString      = "invalid json string"
+	controlCharacterInString = "control character in string must be escaped"
+	unescapedQuoteInString   = "quote in string must be escaped"
+	invalidEscapeInString    = "invalid escape in string"
+	loneSurrogateInString    = "lone UTF-16 surrogate in string"
+	invalidUTF8InString      = "invalid UTF-8 in string"
+)
 
 // Unquote decodes a double-quoted string key or value to retrieve the
 // original string value. It will avoid allocation whenever possible.
-//
-// The code is inspired by strconv.Unquote, but only accepts valid json string.
+// Lone surrogates and invalid UTF-8 are replaced by U+FFFD.
 func Unquote(s []byte) ([]byte, error) {
+	return Unquoter{}.Unquote(s)
+}
+
+// Unquote decodes a double-quoted string key or value to retrieve the
+// original string value. It will avoid allocation whenever possible.
+//
+// Only the escapes of JSON are accepted. Surrogate pairs are combined
+// in the character they encode. Errors are *SyntaxError with an offset in s.
+func (u Unquoter) Unquote(s []byte) ([]byte, error) {
+	n := len(s)
+	if n < 2 || s[0] != '"' || s[n-1] != '"' {
+		return nil, syntaxErr(0, invalidQuotedString, nil)
+	}
+
+	// avoid allocation if the string is trivial
+	if u.isTrivial(s) {
+		return s[1 : n-1], nil
+	}
+	return u.appendUnquoted(make([]byte, 0, n), s)
+}
+
+// UnquoteTo appends to dst the decoded value of the double-quoted string
+// in s. On error, dst is returned as it was.
+func UnquoteTo(dst, s []byte) ([]byte, error) {
+	return Unquoter{}.UnquoteTo(dst, s)
+}
+
+// UnquoteTo appends to dst the decoded value of the double-quoted string
+// in s. On error, dst is returned as it was.
+func (u Unquoter) UnquoteTo(dst, s []byte) ([]byte, error) {
 	n := len(s)
-	if n < 2 {
-		return nil, fmt.Errorf("invalid json string")
+	if n < 2 || s[0] != '"' || s[n-1] != '"' {
+		return dst, syntaxErr(0, invalidQuotedString, nil)
 	}
-	if s[0] != '"' || s[n-1] != '"' {
-		return nil, fmt.Errorf("invalid json string")
+	if u.isTrivial(s) {
+		return append(dst, s[1:n-1]...), nil
 	}
-	s = s[1 : n-1]
-	if bytes.IndexByte(s, '\n') != -1 {
-		return nil, fmt.Errorf("invalid json string")
+	out, err := u.appendUnquoted(dst, s)
+	if err != nil {
+		return dst, err
 	}
+	return out, nil
+}
 
-	// avoid allocation if the string is trivial
-	if bytes.IndexByte(s, '\\') == -1 {
-		if utf8.Valid(s) {
-			return s, nil
+// isTrivial tells if the quoted string s is its own value, once its
+// quotes are removed.
+func (u Unquoter) isTrivial(s []byte) bool {
+	return indexStringSpecial(s[:len(s)-1], 1) == len(s)-1 &&
+		(u.InvalidUTF8 == UTF8Pass || utf8.Valid(s))
+}
+
+// AsString joins the prefixes with dots, with object keys unquoted.
+func (u Unquoter) AsString(data []byte, pfxs Prefixes) (string, error) {
+	var buf []byte
+	for i, pfx := range pfxs {
+		if i != 0 {
+			buf = append(buf, '.')
+		}
+		if pfx.IsArrayIndex() {
+			buf = strconv.AppendInt(buf, int64(pfx.Index()), 10)
+			continue
+		}
+		var err error
+		if buf, err = u.UnquoteTo(buf, pfx.Bytes(data)); err != nil {
+			return "", err
 		}
 	}
+	return string(buf), nil
+}
+
+// Unquote decodes the string value into scratch, reused from its
+// start. Passing the result back as scratch for the next string avoids
+// allocating for each of them.
+func (s String) Unquote(data, scratch []byte) ([]byte, error) {
+	return UnquoteTo(scratch[:0], s.Value.Bytes(data))
+}
+
+// Unquote decodes an object key, or formats an array index, into
+// scratch, reused from its start. Passing the result back as scratch
+// for the next key avoids allocating for each of them.
+func (pfx Prefix) Unquote(data, scratch []byte) ([]byte, error) {
+	if pfx.IsArrayIndex() {
+		return strconv.AppendInt(scratch[:0], int64(pfx.Index()), 10), nil
+	}
+	return UnquoteTo(scratch[:0], pfx.Bytes(data))
+}
 
-	// the following code is taken from strconv.Unquote (with modification)
-	var runeTmp [utf8.UTFMax]byte
-	buf := make([]byte, 0, 3*len(s)/2) // Try to avoid more allocations.
-	for len(s) > 0 {
-		// Convert []byte to string for satisfying UnquoteChar. We won't keep
-		// the retured string, so it's safe to use unsafe here.
-		c, multibyte, tail, err := strconv.UnquoteChar(unsafeBytesToString(s), '"')
-		if err != nil {
-			return nil, err
+// appendUnquoted appends to dst the decoded content of the quoted
+// string s.
+func (u Unquoter) appendUnquoted(dst, s []byte) ([]byte, error) {
+	end := len(s) - 1
+	for i := 1; i < end; {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 == end {
+				return nil, syntaxErr(i, invalidEscapeInString, nil)
+			}
+			switch s[i+1] {
+			case '"', '\\', '/':
+				dst = append(dst, s[i+1])
+			case 'b':
+				dst = append(dst, '\b')
+			case 'f':
+				dst = append(dst, '\f')
+			case 'n':
+				dst = append(dst, '\n')
+			case 'r':
+				dst = append(dst, '\r')
+			case 't':
+				dst = append(dst, '\t')
+			case 'u':
+				r, ok := decodeHex4(s[i+2 : end])
+				if !ok {
+					return nil, syntaxErr(i, unicodeNotFollowHex, nil)
+				}
+				if utf16.IsSurrogate(r) {
+					if r2, ok := decodeEscapedRune(s[i+6 : end]); ok {
+						if pair := utf16.DecodeRune(r, r2); pair != utf8.RuneError {
+							dst = utf8.AppendRune(dst, pair)
+							i += 12
+							continue
+						}
+					}
+					if u.LoneSurrogates == SurrogateError {
+						return nil, syntaxErr(i, loneSurrogateInString, nil)
+					}
+					r = utf8.RuneError
+				}
+				dst = utf8.AppendRune(dst, r)
+				i += 6
+				continue
+			default:
+				return nil, syntaxErr(i, invalidEscapeInString, nil)
+			}
+			i += 2
+		case c == '"':
+			return nil, syntaxErr(i, unescapedQuoteInString, nil)
+		case c < ' ':
+			return nil, syntaxErr(i, controlCharacterInString, nil)
+		case c < utf8.RuneSelf:
+			dst = append(dst, c)
+			i++
+		default:
+			r, size := utf8.DecodeRune(s[i:end])
+			switch {
+			case r != utf8.RuneError || size != 1:
+				dst = append(dst, s[i:i+size]...)
+			case u.InvalidUTF8 == UTF8Pass:
+				dst = append(dst, c)
+			case u.InvalidUTF8 == UTF8Error:
+				return nil, syntaxErr(i, invalidUTF8InString, nil)
+			default:
+				dst = utf8.AppendRune(dst, utf8.RuneError)
+			}
+			i += size
 		}
+	}
+	return dst, nil
+}
 
-		// UnquoteChar returns tail as the remaining unprocess string. Because
-		// we are processing []byte, we use len(tail) to get the remaining bytes
-		// instead.
-		s = s[len(s)-len(tail):]
-		if c < utf8.RuneSelf || !multibyte {
-			buf = append(buf, byte(c))
-		} else {
-			n = utf8.EncodeRune(runeTmp[:], c)
-			buf = append(buf, runeTmp[:n]...)
+// indexInvalidUTF8 returns the index of the first byte of s that isn't
+// valid UTF-8, or -1.
+func indexInvalidUTF8(s []byte) int {
+	if utf8.Valid(s) {
+		return -1
+	}
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRune(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			return i
+		}
+		i += size
+	}
+	return -1
+}
+
+// decodeEscapedRune decodes the `\u` escape at the start of s.
+func decodeEscapedRune(s []byte) (rune, bool) {
+	if len(s) < 6 || s[0] != '\\' || s[1] != 'u' {
+		return 0, false
+	}
+	return decodeHex4(s[2:])
+}
+
+// decodeHex4 decodes the 4 hex digits at the start of s.
+func decodeHex4(s []byte) (rune, bool) {
+	if len(s) < 4 {
+		return 0, false
+	}
+	var r rune
+	for _, b := range s[:4] {
+		switch {
+		case b >= '0' && b <= '9':
+			b -= '0'
+		case b >= 'a' && b <= 'f':
+			b -= 'a' - 10
+		case b >= 'A' && b <= 'F':
+			b -= 'A' - 10
+		default:
+			return 0, false
 		}
+		r = r<<4 | rune(b)
 	}
-	return buf, nil
+	return r, true
 }
 
 //go:nosplit
@@ -0,0 +1,360 @@
+package flatjson
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestUnquote(t *testing.T) {
+	tests := []struct {
+		Name string
+		Data string
+		Want string
+	}{
+		{Name: "plain", Data: `"hello"`, Want: "hello"},
+		{Name: "empty", Data: `""`, Want: ""},
+		{Name: "escapes", Data: `"\"\\\/\b\f\n\r\t"`, Want: "\"\\/\b\f\n\r\t"},
+		{Name: "unicode", Data: `"\u00e9\u20AC"`, Want: "é€"},
+		{Name: "utf-8", Data: `"é😀"`, Want: "é😀"},
+		{Name: "surrogate pair", Data: `"a\ud83d\ude00b"`, Want: "a😀b"},
+		{Name: "uppercase surrogate pair", Data: `"\uD834\uDD1E"`, Want: "𝄞"},
+		{Name: "lone high surrogate", Data: `"\ud83d"`, Want: "�"},
+		{Name: "lone high surrogate before text", Data: `"\ud83dab"`, Want: "�ab"},
+		{Name: "lone low surrogate", Data: `"\ude00\ud83d"`, Want: "��"},
+		{Name: "high surrogate before escape", Data: `"\ud83d\u0041"`, Want: "�A"},
+		{Name: "two high surrogates", Data: `"\ud83d\ud83d\ude00"`, Want: "�😀"},
+		{Name: "invalid utf-8", Data: "\"a\xffb\"", Want: "a�b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			got, err := Unquote([]byte(tt.Data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.Want {
+				t.Errorf("want %q", tt.Want)
+				t.Errorf(" got %q", got)
+			}
+		})
+	}
+}
+
+func TestUnquoteErrors(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Data          string
+		Unquoter      Unquoter
+		WantErrError  string
+		WantErrOffset int
+	}{
+		{Name: "not quoted", Data: `hello`, WantErrError: invalidQuotedString, WantErrOffset: 0},
+		{Name: "unterminated", Data: `"hello`, WantErrError: invalidQuotedString, WantErrOffset: 0},
+		{Name: "go hex escape", Data: `"\x41"`, WantErrError: invalidEscapeInString, WantErrOffset: 1},
+		{Name: "go bell escape", Data: `"a\a"`, WantErrError: invalidEscapeInString, WantErrOffset: 2},
+		{Name: "go single quote escape", Data: `"\'"`, WantErrError: invalidEscapeInString, WantErrOffset: 1},
+		{Name: "go octal escape", Data: `"\101"`, WantErrError: invalidEscapeInString, WantErrOffset: 1},
+		{Name: "go long unicode escape", Data: `"\U0001F600"`, WantErrError: invalidEscapeInString, WantErrOffset: 1},
+		{Name: "trailing backslash", Data: `"\"`, WantErrError: invalidEscapeInString, WantErrOffset: 1},
+		{Name: "short unicode escape", Data: `"\u12"`, WantErrError: unicodeNotFollowHex, WantErrOffset: 1},
+		{Name: "non-hex unicode escape", Data: `"\u12g4"`, WantErrError: unicodeNotFollowHex, WantErrOffset: 1},
+		{Name: "unescaped quote", Data: `"a"b"`, WantErrError: unescapedQuoteInString, WantErrOffset: 2},
+		{Name: "newline", Data: "\"a\nb\"", WantErrError: controlCharacterInString, WantErrOffset: 2},
+		{Name: "tab", Data: "\"a\tb\"", WantErrError: controlCharacterInString, WantErrOffset: 2},
+		{
+			Name:          "lone surrogate",
+			Data:          `"ab\ud83d"`,
+			Unquoter:      Unquoter{LoneSurrogates: SurrogateError},
+			WantErrError:  loneSurrogateInString,
+			WantErrOffset: 3,
+		},
+		{
+			Name:          "reversed surrogates",
+			Data:          `"\ude00\ud83d"`,
+			Unquoter:      Unquoter{LoneSurrogates: SurrogateError},
+			WantErrError:  loneSurrogateInString,
+			WantErrOffset: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			_, err := tt.Unquoter.Unquote([]byte(tt.Data))
+			serr, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("want a syntax error, got %v", err)
+			}
+			if serr.Message != tt.WantErrError {
+				t.Errorf("want %q", tt.WantErrError)
+				t.Errorf(" got %q", serr.Message)
+			}
+			if serr.Offset != tt.WantErrOffset {
+				t.Errorf("want offset %d", tt.WantErrOffset)
+				t.Errorf(" got offset %d", serr.Offset)
+			}
+		})
+	}
+
+	got, err := Unquoter{LoneSurrogates: SurrogateError}.Unquote([]byte(`"\ud83d\ude00"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "😀" {
+		t.Errorf("want %q, got %q", "😀", got)
+	}
+}
+
+func TestUnquoteTo(t *testing.T) {
+	dst := []byte("prefix:")
+	got, err := UnquoteTo(dst, []byte(`"a😀"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "prefix:a😀"; string(got) != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	got, err = UnquoteTo(got, []byte(`"bc"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "prefix:a😀bc"; string(got) != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	got, err = UnquoteTo(dst, []byte(`"a\x41"`))
+	if err == nil {
+		t.Errorf("want an error")
+	}
+	if string(got) != "prefix:" {
+		t.Errorf("want dst unchanged, got %q", got)
+	}
+}
+
+func TestUnquoteScratch(t *testing.T) {
+	data := []byte(`{"key":"v\u00e9","plain":"text","list":[1]}`)
+	var (
+		keys, values []string
+		scratch      []byte
+		err          error
+	)
+	_, _, err = ScanObject(data, 0, &Callbacks{
+		MaxDepth: 2,
+		OnString: func(prefixes Prefixes, s String) {
+			if scratch, err = s.Name.Unquote(data, scratch); err != nil {
+				t.Fatal(err)
+			}
+			keys = append(keys, string(scratch))
+			if scratch, err = s.Unquote(data, scratch); err != nil {
+				t.Fatal(err)
+			}
+			values = append(values, string(scratch))
+		},
+		OnInteger: func(prefixes Prefixes, i Integer) {
+			if scratch, err = i.Name.Unquote(data, scratch); err != nil {
+				t.Fatal(err)
+			}
+			keys = append(keys, string(scratch))
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "key,plain,0"; strings.Join(keys, ",") != want {
+		t.Errorf("want keys %q", want)
+		t.Errorf(" got keys %q", strings.Join(keys, ","))
+	}
+	if want := "vé,text"; strings.Join(values, ",") != want {
+		t.Errorf("want values %q", want)
+		t.Errorf(" got values %q", strings.Join(values, ","))
+	}
+	if string(data) != `{"key":"v\u00e9","plain":"text","list":[1]}` {
+		t.Errorf("data was modified: %s", data)
+	}
+
+	s := String{Value: Pos{From: 7, To: 16}}
+	allocs := testing.AllocsPerRun(100, func() {
+		scratch, _ = s.Unquote(data, scratch)
+	})
+	if allocs != 0 {
+		t.Errorf("want no allocation, got %v", allocs)
+	}
+	if string(scratch) != "vé" {
+		t.Errorf("want %q, got %q", "vé", scratch)
+	}
+}
+
+func TestUTF8Policy(t *testing.T) {
+	// a mobile client cut a multi-byte character short
+	raw := []byte("\"caf\xc3\"")
+
+	tests := []struct {
+		Policy        UTF8Policy
+		Want          string
+		WantErrOffset int
+	}{
+		{Policy: UTF8Replace, Want: "caf�", WantErrOffset: -1},
+		{Policy: UTF8Pass, Want: "caf\xc3", WantErrOffset: -1},
+		{Policy: UTF8Error, WantErrOffset: 4},
+	}
+	for _, tt := range tests {
+		u := Unquoter{InvalidUTF8: tt.Policy}
+		got, err := u.Unquote(raw)
+		if tt.WantErrOffset >= 0 {
+			serr, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("policy %d: want a syntax error, got %v", tt.Policy, err)
+			}
+			if serr.Message != invalidUTF8InString || serr.Offset != tt.WantErrOffset {
+				t.Errorf("policy %d: want %q at %d", tt.Policy, invalidUTF8InString, tt.WantErrOffset)
+				t.Errorf("policy %d:  got %q at %d", tt.Policy, serr.Message, serr.Offset)
+			}
+			if u.Equal(raw, "caf\xc3") || u.Equal(raw, "caf�") {
+				t.Errorf("policy %d: want invalid strings to never be equal", tt.Policy)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("policy %d: %v", tt.Policy, err)
+		}
+		if string(got) != tt.Want {
+			t.Errorf("policy %d: want %q", tt.Policy, tt.Want)
+			t.Errorf("policy %d:  got %q", tt.Policy, got)
+		}
+		if !u.Equal(raw, tt.Want) || !u.EqualFold(raw, "CAF"+tt.Want[3:]) {
+			t.Errorf("policy %d: want equal to %q", tt.Policy, tt.Want)
+		}
+		data := []byte("{" + string(raw) + ":1}")
+		name := newObjectKeyPrefix(1, 1+len(raw))
+		path, err := u.AsString(data, Prefixes{name, newArrayIndexPrefix(2)})
+		if err != nil {
+			t.Fatalf("policy %d: %v", tt.Policy, err)
+		}
+		if want := tt.Want + ".2"; path != want {
+			t.Errorf("policy %d: want path %q", tt.Policy, want)
+			t.Errorf("policy %d:  got path %q", tt.Policy, path)
+		}
+	}
+}
+
+func TestScanUTF8Policy(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Data          string
+		WantErrOffset int
+	}{
+		{Name: "valid", Data: `{"café":"naïve","list":["😀"]}`, WantErrOffset: -1},
+		{Name: "key", Data: "{\"a\":1,\"caf\xc3\":2}", WantErrOffset: 11},
+		{Name: "value", Data: "{\"a\":\"\xff\"}", WantErrOffset: 6},
+		{Name: "nested value", Data: "{\"a\":{\"b\":[\"ok\",\"x\xe2\x82\"]}}", WantErrOffset: 18},
+		{Name: "surrogate encoded in utf-8", Data: "{\"a\":\"\xed\xa0\xbd\"}", WantErrOffset: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var count int
+			cb := &Callbacks{
+				MaxDepth: 3,
+				UTF8:     UTF8Error,
+				OnString: func(Prefixes, String) { count++ },
+			}
+			_, _, err := ScanObject([]byte(tt.Data), 0, cb)
+			if tt.WantErrOffset < 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if count != 2 {
+					t.Errorf("want 2 strings, got %d", count)
+				}
+				doc := Document{UTF8: UTF8Error}
+				if err := doc.Reset([]byte(tt.Data)); err != nil {
+					t.Errorf("document: %v", err)
+				}
+				tok := NewTokenizer([]byte(tt.Data))
+				tok.UTF8 = UTF8Error
+				if err := tokenizeAll(tok); err != nil {
+					t.Errorf("tokenizer: %v", err)
+				}
+				return
+			}
+			wantUTF8Err(t, "scan", err, tt.WantErrOffset)
+
+			// documents and tokenizers find it too
+			doc := Document{UTF8: UTF8Error}
+			wantUTF8Err(t, "document", doc.Reset([]byte(tt.Data)), tt.WantErrOffset)
+			tok := NewTokenizer(nil)
+			tok.UTF8 = UTF8Error
+			tok.Reset([]byte(tt.Data))
+			wantUTF8Err(t, "tokenizer", tokenizeAll(tok), tt.WantErrOffset)
+
+			// it's found even where nothing is reported
+			for _, depth := range []int{0, 1} {
+				shallow := &Callbacks{MaxDepth: depth, UTF8: UTF8Error}
+				if _, _, err := ScanObject([]byte(tt.Data), 0, shallow); err == nil {
+					t.Errorf("max depth %d: want an error", depth)
+				}
+			}
+
+			// other policies leave it to the unquoter
+			cb.UTF8 = UTF8Pass
+			if _, _, err := ScanObject([]byte(tt.Data), 0, cb); err != nil {
+				t.Errorf("pass: %v", err)
+			}
+		})
+	}
+}
+
+func wantUTF8Err(t *testing.T, what string, err error, offset int) {
+	t.Helper()
+	serr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("%s: want a syntax error, got %v", what, err)
+	}
+	for serr.SubErr != nil {
+		serr = serr.SubErr
+	}
+	if serr.Message != invalidUTF8InString || serr.Offset != offset {
+		t.Errorf("%s: want %q at %d", what, invalidUTF8InString, offset)
+		t.Errorf("%s:  got %q at %d", what, serr.Message, serr.Offset)
+	}
+}
+
+// tokenizeAll reads the tokens of t until the end of its data.
+func tokenizeAll(t *Tokenizer) error {
+	for {
+		if _, err := t.Next(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func TestStrUTF8Policy(t *testing.T) {
+	data := []byte("{\"a\":\"caf\xc3\"}")
+	for policy, want := range map[UTF8Policy]string{UTF8Replace: "caf\ufffd", UTF8Pass: "caf\xc3"} {
+
+		var got []byte
+		cb := &Callbacks{
+			MaxDepth: 1,
+			UTF8:     policy,
+			OnValue: func(_ Prefixes, val Value) {
+				got, _ = val.Str(data)
+			},
+		}
+		if _, _, err := ScanObject(data, 0, cb); err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("policy %d: want value %q", policy, want)
+			t.Errorf("policy %d:  got value %q", policy, got)
+		}
+
+		doc := Document{UTF8: policy}
+		if err := doc.Reset(data); err != nil {
+			t.Fatal(err)
+		}
+		node, _ := doc.Get("a")
+		if got, _ := node.Str(); string(got) != want {
+			t.Errorf("policy %d: want node %q", policy, want)
+			t.Errorf("policy %d:  got node %q", policy, got)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package flatjson
+
+var entityTypeNames = [...]string{
+	EntityType_Invalid:       "invalid",
+	EntityType_String:        "string",
+	EntityType_Object:        "object",
+	EntityType_Array:         "array",
+	EntityType_Number:        "number",
+	EntityType_Boolean_True:  "true",
+	EntityType_Boolean_False: "false",
+	EntityType_Null:          "null",
+}
+
+func (et EntityType) String() string {
+	if int(et) < len(entityTypeNames) {
+		return entityTypeNames[et]
+	}
+	return entityTypeNames[EntityType_Invalid]
+}
+
+// Value is a value found in JSON data: what kind it is, its name in its
+// object or array, and where it is. Only numbers are decoded as they're
+// found; strings are decoded when asked for.
+type Value struct {
+	Kind EntityType
+	Name Prefix
+	Pos  Pos
+
+	num numberValue
+	// u decodes strings with the UTF8 policy of the scan
+	u Unquoter
+}
+
+// numberValue is a number as it was scanned.
+type numberValue struct {
+	f64   float64
+	i64   int64
+	isInt bool
+}
+
+// newValue is the value at pos, named name, decoding it if it's a
+// number.
+func newValue(data []byte, name Prefix, pos Pos) Value {
+	v := Value{Kind: GuessNextEntityType(data, pos.From), Name: name, Pos: pos}
+	if v.Kind == EntityType_Number {
+		v.num.f64, v.num.i64, v.num.isInt, _, _ = scanNumber(data, pos.From)
+	}
+	return v
+}
+
+// Bytes of the value, as found in data.
+func (v Value) Bytes(data []byte) []byte { return v.Pos.Bytes(data) }
+
+// Int64 value of an integer number.
+func (v Value) Int64() (int64, bool) {
+	return v.num.i64, v.Kind == EntityType_Number && v.num.isInt
+}
+
+// Float64 value of a number.
+func (v Value) Float64() (float64, bool) {
+	if v.num.isInt {
+		return float64(v.num.i64), v.Kind == EntityType_Number
+	}
+	return v.num.f64, v.Kind == EntityType_Number
+}
+
+// Str is the unquoted value of a string, decoded with the UTF8 policy
+// of the scan that found it. It refers to data whenever the string has
+// no escape sequences.
+func (v Value) Str(data []byte) ([]byte, bool) {
+	if v.Kind != EntityType_String {
+		return nil, false
+	}
+	s, err := v.u.Unquote(v.Bytes(data))
+	return s, err == nil
+}
+
+// Bool value of a boolean.
+func (v Value) Bool() (bool, bool) {
+	switch v.Kind {
+	case EntityType_Boolean_True:
+		return true, true
+	case EntityType_Boolean_False:
+		return false, true
+	}
+	return false, false
+}
+
+// IsNull tells if the value is null.
+func (v Value) IsNull() bool { return v.Kind == EntityType_Null }
@@ -0,0 +1,98 @@
+package flatjson
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestOnValue(t *testing.T) {
+	data := []byte(`{"a":1,"b":-2.5,"c":"d\n","e":[true,false,null],"f":{},"a":3}`)
+	var got []string
+	_, _, err := ScanObject(data, 0, &Callbacks{
+		MaxDepth:      1,
+		DuplicateKeys: DuplicateKeysFirstWins,
+		OnValue: func(prefixes Prefixes, v Value) {
+			var s string
+			switch v.Kind {
+			case EntityType_Number:
+				if i64, ok := v.Int64(); ok {
+					s = fmt.Sprint(i64)
+				} else {
+					f64, _ := v.Float64()
+					s = fmt.Sprint(f64)
+				}
+			case EntityType_String:
+				str, _ := v.Str(data)
+				s = fmt.Sprintf("%q", str)
+			case EntityType_Boolean_True, EntityType_Boolean_False:
+				b, _ := v.Bool()
+				s = fmt.Sprint(b)
+			default:
+				s = v.Pos.String(data)
+			}
+			path, _ := Unquoter{}.AsString(data, append(prefixes, v.Name))
+			got = append(got, fmt.Sprintf("%s %v %s", path, v.Kind, s))
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`a number 1`,
+		`b number -2.5`,
+		`c string "d\n"`,
+		`e.0 true true`,
+		`e.1 false false`,
+		`e.2 null null`,
+		`e array [true,false,null]`,
+		`f object {}`,
+	}
+	if strings.Join(want, "\n") != strings.Join(got, "\n") {
+		t.Errorf("want %q", want)
+		t.Errorf(" got %q", got)
+	}
+}
+
+func TestValueAccessorsOfOtherKinds(t *testing.T) {
+	data := []byte(`"1"`)
+	v := newValue(data, Prefix{}, Pos{0, 3})
+	if _, ok := v.Int64(); ok {
+		t.Errorf("want no integer of a string")
+	}
+	if _, ok := v.Float64(); ok {
+		t.Errorf("want no float of a string")
+	}
+	if _, ok := v.Bool(); ok {
+		t.Errorf("want no boolean of a string")
+	}
+	if v.IsNull() {
+		t.Errorf("want a string not to be null")
+	}
+	if s, ok := v.Str(data); !ok || string(s) != "1" {
+		t.Errorf("want %q, got %q", "1", s)
+	}
+
+	data = []byte(`1e2`)
+	v = newValue(data, Prefix{}, Pos{0, 3})
+	if f64, ok := v.Float64(); !ok || f64 != 100 {
+		t.Errorf("want %v, got %v", 100, f64)
+	}
+	if _, ok := v.Str(data); ok {
+		t.Errorf("want no string of a number")
+	}
+}
+
+func TestEntityTypeString(t *testing.T) {
+	for et, want := range map[EntityType]string{
+		EntityType_Invalid:       "invalid",
+		EntityType_Object:        "object",
+		EntityType_Boolean_False: "false",
+		EntityType_Null:          "null",
+		42:                       "invalid",
+	} {
+		if got := et.String(); want != got {
+			t.Errorf("want %q, got %q", want, got)
+		}
+	}
+}
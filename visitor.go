@@ -0,0 +1,41 @@
+package flatjson
+
+import "math"
+
+// Visitor is told about the values a scan finds, as Callbacks are, by
+// methods instead of fields.
+type Visitor interface {
+	VisitFloat(prefixes Prefixes, val Float)
+	VisitInteger(prefixes Prefixes, val Integer)
+	VisitString(prefixes Prefixes, val String)
+	VisitBoolean(prefixes Prefixes, val Bool)
+	VisitNull(prefixes Prefixes, val Null)
+}
+
+// VisitorCallbacks returns callbacks telling v about every value, at
+// any depth. Lower their MaxDepth, or set their other options, before
+// scanning with them.
+func VisitorCallbacks(v Visitor) Callbacks {
+	return Callbacks{
+		MaxDepth:  math.MaxInt,
+		OnFloat:   v.VisitFloat,
+		OnInteger: v.VisitInteger,
+		OnString:  v.VisitString,
+		OnBoolean: v.VisitBoolean,
+		OnNull:    v.VisitNull,
+	}
+}
+
+// ScanObjectWith is ScanObject, telling v about every value nested in
+// the object, at any depth.
+func ScanObjectWith(data []byte, from int, v Visitor) (pos Pos, found bool, err error) {
+	cb := VisitorCallbacks(v)
+	return ScanObject(data, from, &cb)
+}
+
+// ScanArrayWith is ScanArray, telling v about every value nested in the
+// array, at any depth.
+func ScanArrayWith(data []byte, from int, v Visitor) (pos Pos, found bool, err error) {
+	cb := VisitorCallbacks(v)
+	return ScanArray(data, from, &cb)
+}
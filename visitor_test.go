@@ -0,0 +1,130 @@
+package flatjson
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// collector writes down what it visits.
+type collector struct {
+	data []byte
+	got  *[]string
+}
+
+func (c collector) add(prefixes Prefixes, name Prefix, val any) {
+	path, _ := Unquoter{}.AsString(c.data, append(prefixes, name))
+	*c.got = append(*c.got, fmt.Sprintf("%s=%v", path, val))
+}
+
+func (c collector) VisitFloat(prefixes Prefixes, val Float) { c.add(prefixes, val.Name, val.Value) }
+func (c collector) VisitInteger(prefixes Prefixes, val Integer) {
+	c.add(prefixes, val.Name, val.Value)
+}
+func (c collector) VisitString(prefixes Prefixes, val String) {
+	c.add(prefixes, val.Name, val.Value.String(c.data))
+}
+func (c collector) VisitBoolean(prefixes Prefixes, val Bool) { c.add(prefixes, val.Name, val.Value) }
+func (c collector) VisitNull(prefixes Prefixes, val Null)    { c.add(prefixes, val.Name, nil) }
+
+func TestScanObjectWith(t *testing.T) {
+	data := []byte(`{"a":1,"b":[2.5,"c",{"d":true}],"e":{"f":null,"g":false}}`)
+
+	var got []string
+	if _, _, err := ScanObjectWith(data, 0, collector{data: data, got: &got}); err != nil {
+		t.Fatal(err)
+	}
+
+	var want []string
+	c := collector{data: data, got: &want}
+	_, _, err := ScanObject(data, 0, &Callbacks{
+		MaxDepth:  math.MaxInt,
+		OnFloat:   c.VisitFloat,
+		OnInteger: c.VisitInteger,
+		OnString:  c.VisitString,
+		OnBoolean: c.VisitBoolean,
+		OnNull:    c.VisitNull,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(want, " ") != strings.Join(got, " ") {
+		t.Errorf("want %q", want)
+		t.Errorf(" got %q", got)
+	}
+	if len(got) != 6 {
+		t.Errorf("want 6 values, got %d", len(got))
+	}
+
+	got = got[:0]
+	if _, _, err := ScanArrayWith(data, 0, collector{data: data, got: &got}); err == nil {
+		t.Errorf("want an error scanning an object as an array")
+	}
+	arr := []byte(`[1,[true]]`)
+	if _, _, err := ScanArrayWith(arr, 0, collector{data: arr, got: &got}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "0=1 1.0=true"; want != strings.Join(got, " ") {
+		t.Errorf("want %s", want)
+		t.Errorf(" got %s", strings.Join(got, " "))
+	}
+}
+
+func TestVisitorCallbacksDepth(t *testing.T) {
+	data := []byte(`{"a":1,"b":[2.5,"c",{"d":true}],"e":{"f":null,"g":false}}`)
+
+	var got []string
+	cb := VisitorCallbacks(collector{data: data, got: &got})
+	cb.MaxDepth = 1
+	if _, _, err := ScanObject(data, 0, &cb); err != nil {
+		t.Fatal(err)
+	}
+	if want := `a=1 b.0=2.5 b.1="c" e.f=<nil> e.g=false`; want != strings.Join(got, " ") {
+		t.Errorf("want %s", want)
+		t.Errorf(" got %s", strings.Join(got, " "))
+	}
+}
+
+func TestCallbacksIntegersAsFloats(t *testing.T) {
+	data := []byte(`{"a":3,"b":[4.5]}`)
+	var got []float64
+	_, _, err := ScanObject(data, 0, &Callbacks{
+		MaxDepth: 1,
+		OnFloat:  func(_ Prefixes, val Float) { got = append(got, val.Value) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[3 4.5]"; want != fmt.Sprint(got) {
+		t.Errorf("want %s", want)
+		t.Errorf(" got %v", got)
+	}
+}
+
+// counter counts what it visits.
+type counter struct{ n *int }
+
+func (c counter) VisitFloat(Prefixes, Float)     { *c.n++ }
+func (c counter) VisitInteger(Prefixes, Integer) { *c.n++ }
+func (c counter) VisitString(Prefixes, String)   { *c.n++ }
+func (c counter) VisitBoolean(Prefixes, Bool)    { *c.n++ }
+func (c counter) VisitNull(Prefixes, Null)       { *c.n++ }
+
+func TestScanObjectWithDoesntAllocate(t *testing.T) {
+	// nested objects and arrays allocate their prefixes
+	data := []byte(`{"a":1,"b":2.5,"c":"d","e":true,"f":null}`)
+	var n int
+	allocs := testing.AllocsPerRun(100, func() {
+		n = 0
+		if _, _, err := ScanObjectWith(data, 0, counter{&n}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("want no allocation, got %v", allocs)
+	}
+	if n != 5 {
+		t.Errorf("want 5 values, got %d", n)
+	}
+}